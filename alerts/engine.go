@@ -0,0 +1,298 @@
+package alerts
+
+import (
+	"fmt"
+	"sort"
+	"time"
+	"wb-assistance-logistic/config"
+	"wb-assistance-logistic/snapshot"
+)
+
+// Event is a change of an alert state
+type Event struct {
+	Rule     *Rule
+	Key      int
+	Resolved bool
+	Time     time.Time
+	Message  string
+}
+
+// state of a rule for a single key
+type state struct {
+	firing  bool
+	pending int // Consecutive evaluations meeting the condition opposite to the current state
+
+	seen       bool
+	value      int
+	changedAt  time.Time
+	firedValue int // Value the unchanged alert fired on
+}
+
+// Engine evaluates the rules against every parsed snapshot and keeps the alert states between the ticks
+type Engine struct {
+	rules     []*Rule
+	keyColumn string
+	keyIndex  int
+	states    map[string]map[int]*state
+}
+
+func newEngine(rules []*Rule, keyColumn string, keyIndex int) *Engine {
+	states := make(map[string]map[int]*state, len(rules))
+	for _, rule := range rules {
+		states[rule.Name] = map[int]*state{}
+	}
+
+	return &Engine{
+		rules:     rules,
+		keyColumn: keyColumn,
+		keyIndex:  keyIndex,
+		states:    states,
+	}
+}
+
+// NewEngineByConfig validates the rules against the columns of the parsed rows
+func NewEngineByConfig(cfg *config.Alerts, keyColumn string, columns []string) (*Engine, error) {
+	keyIndex := -1
+	for i, column := range columns {
+		if column == keyColumn {
+			keyIndex = i
+		}
+	}
+
+	if keyIndex < 0 {
+		return nil, fmt.Errorf("key column %q is not in the columns", keyColumn)
+	}
+
+	rules := make([]*Rule, 0, len(cfg.Rules))
+	names := map[string]bool{}
+
+	for _, ruleCfg := range cfg.Rules {
+		rule, err := newRuleByConfig(ruleCfg, columns)
+		if err != nil {
+			return nil, err
+		}
+
+		if names[rule.Name] {
+			return nil, fmt.Errorf("duplicate rule name %q", rule.Name)
+		}
+		names[rule.Name] = true
+
+		rules = append(rules, rule)
+	}
+
+	return newEngine(rules, keyColumn, keyIndex), nil
+}
+
+// Evaluate updates the alert states by the snapshot and returns the alerts fired or resolved by it
+func (e *Engine) Evaluate(s *snapshot.Snapshot) []*Event {
+	rows := make(map[int][]int, len(s.Rows))
+	for _, row := range s.Rows {
+		if e.keyIndex < len(row) {
+			rows[row[e.keyIndex]] = row
+		}
+	}
+
+	var events []*Event
+	for _, rule := range e.rules {
+		switch rule.Type {
+		case THRESHOLD_RULE:
+			events = append(events, e.evaluateThreshold(rule, rows, s.Time)...)
+		case UNCHANGED_RULE:
+			events = append(events, e.evaluateUnchanged(rule, rows, s.Time)...)
+		case MISSING_RULE:
+			events = append(events, e.evaluateMissing(rule, rows, s.Time)...)
+		}
+	}
+
+	return events
+}
+
+func (e *Engine) state(rule *Rule, key int) *state {
+	st, ok := e.states[rule.Name][key]
+	if !ok {
+		st = &state{}
+		e.states[rule.Name][key] = st
+	}
+
+	return st
+}
+
+// update applies the hysteresis: the state changes after ForTicks evaluations meeting the condition
+// and goes back after ClearTicks evaluations meeting the clear condition
+func (st *state) update(rule *Rule, fire, clear bool) (changed bool) {
+	var matched bool
+	var required int
+
+	if st.firing {
+		matched, required = clear, rule.ClearTicks
+	} else {
+		matched, required = fire, rule.ForTicks
+	}
+
+	if !matched {
+		st.pending = 0
+		return false
+	}
+
+	st.pending++
+	if st.pending < required {
+		return false
+	}
+
+	st.firing = !st.firing
+	st.pending = 0
+
+	return true
+}
+
+func (e *Engine) evaluateThreshold(rule *Rule, rows map[int][]int, now time.Time) []*Event {
+	var events []*Event
+
+	// A key missing from the report keeps its state, the disappearance is covered by the missing rules
+	for _, key := range sortedKeys(rows) {
+		row := rows[key]
+		if !rule.appliesTo(key) || rule.fieldIndex >= len(row) {
+			continue
+		}
+
+		value := row[rule.fieldIndex]
+		st := e.state(rule, key)
+
+		if !st.update(rule, rule.triggered(float64(value)), rule.cleared(float64(value))) {
+			continue
+		}
+
+		event := e.event(rule, key, !st.firing, now)
+		if st.firing {
+			event.Message = fmt.Sprintf("%s: %s %d, %s = %d (%s %g)",
+				rule.Name, e.keyColumn, key, rule.Field, value, rule.Op, rule.Value)
+		} else {
+			event.Message = fmt.Sprintf("%s resolved: %s %d, %s = %d",
+				rule.Name, e.keyColumn, key, rule.Field, value)
+		}
+		events = append(events, event)
+	}
+
+	return events
+}
+
+func (e *Engine) evaluateUnchanged(rule *Rule, rows map[int][]int, now time.Time) []*Event {
+	var events []*Event
+
+	for _, key := range sortedKeys(rows) {
+		row := rows[key]
+		if !rule.appliesTo(key) || rule.fieldIndex >= len(row) {
+			continue
+		}
+
+		value := row[rule.fieldIndex]
+		st := e.state(rule, key)
+
+		changed := !st.seen || st.value != value
+		if changed {
+			st.seen = true
+			st.value = value
+			st.changedAt = now
+		}
+
+		// The alert clears once the value differs from the one it fired on for ClearTicks evaluations
+		if !st.update(rule, now.Sub(st.changedAt) >= rule.Duration, value != st.firedValue) {
+			continue
+		}
+
+		if st.firing {
+			st.firedValue = value
+		}
+
+		event := e.event(rule, key, !st.firing, now)
+		if st.firing {
+			event.Message = fmt.Sprintf("%s: %s %d, %s = %d unchanged for %s",
+				rule.Name, e.keyColumn, key, rule.Field, value, now.Sub(st.changedAt).Round(time.Second))
+		} else {
+			event.Message = fmt.Sprintf("%s resolved: %s %d, %s changed to %d",
+				rule.Name, e.keyColumn, key, rule.Field, value)
+		}
+		events = append(events, event)
+	}
+
+	return events
+}
+
+func (e *Engine) evaluateMissing(rule *Rule, rows map[int][]int, now time.Time) []*Event {
+	// A rule without a key watches every key seen in the previous reports. The state of an absent key
+	// either counts the ticks towards its alert or holds the firing alert until the key is back, so it is never dropped.
+	keys := map[int][]int{}
+	if rule.Key != nil {
+		keys[*rule.Key] = nil
+	} else {
+		for key := range e.states[rule.Name] {
+			keys[key] = nil
+		}
+		for key := range rows {
+			keys[key] = nil
+		}
+	}
+
+	var events []*Event
+	for _, key := range sortedKeys(keys) {
+		_, present := rows[key]
+		st := e.state(rule, key)
+
+		if !st.update(rule, !present, present) {
+			continue
+		}
+
+		event := e.event(rule, key, !st.firing, now)
+		if st.firing {
+			event.Message = fmt.Sprintf("%s: %s %d disappeared from the report", rule.Name, e.keyColumn, key)
+		} else {
+			event.Message = fmt.Sprintf("%s resolved: %s %d is back in the report", rule.Name, e.keyColumn, key)
+		}
+		events = append(events, event)
+	}
+
+	return events
+}
+
+func (e *Engine) event(rule *Rule, key int, resolved bool, now time.Time) *Event {
+	return &Event{
+		Rule:     rule,
+		Key:      key,
+		Resolved: resolved,
+		Time:     now,
+	}
+}
+
+// NewFormatEvent returns the alert of a changed or restored bot reply format, it is not tied to a key
+func NewFormatEvent(resolved bool, message string, now time.Time) *Event {
+	return &Event{
+		Rule:     FormatRule,
+		Resolved: resolved,
+		Time:     now,
+		Message:  message,
+	}
+}
+
+// Firing returns the number of alerts currently firing
+func (e *Engine) Firing() int {
+	count := 0
+	for _, states := range e.states {
+		for _, st := range states {
+			if st.firing {
+				count++
+			}
+		}
+	}
+
+	return count
+}
+
+func sortedKeys(rows map[int][]int) []int {
+	keys := make([]int, 0, len(rows))
+	for key := range rows {
+		keys = append(keys, key)
+	}
+	sort.Ints(keys)
+
+	return keys
+}
@@ -0,0 +1,164 @@
+package alerts
+
+import (
+	"strings"
+	"testing"
+	"time"
+	"wb-assistance-logistic/config"
+	"wb-assistance-logistic/snapshot"
+)
+
+var testColumns = []string{"Парковка", "Коробок"}
+
+func float(v float64) *float64 {
+	return &v
+}
+
+// tick is the value of the key 1 in a report, nil when the key is missing from it
+type tick *int
+
+func present(v int) tick {
+	return &v
+}
+
+var absent tick = nil
+
+func TestEngineHysteresis(t *testing.T) {
+	tests := []struct {
+		name  string
+		rule  *config.AlertRule
+		ticks []tick
+		want  []string // The event of every tick: "fire", "resolve" or empty
+	}{
+		{
+			name:  "fire and resolve",
+			rule:  &config.AlertRule{Type: "threshold", Field: "Коробок", Op: ">", Value: 10},
+			ticks: []tick{present(5), present(11), present(12), present(9)},
+			want:  []string{"", "fire", "", "resolve"},
+		},
+		{
+			name:  "hold for ticks",
+			rule:  &config.AlertRule{Type: "threshold", Field: "Коробок", Op: ">", Value: 10, ForTicks: 3},
+			ticks: []tick{present(11), present(11), present(5), present(11), present(11), present(11)},
+			want:  []string{"", "", "", "", "", "fire"},
+		},
+		{
+			name:  "clear after ticks",
+			rule:  &config.AlertRule{Type: "threshold", Field: "Коробок", Op: ">", Value: 10, ClearTicks: 2},
+			ticks: []tick{present(11), present(5), present(11), present(5), present(4)},
+			want:  []string{"fire", "", "", "", "resolve"},
+		},
+		{
+			name:  "missing key keeps the state",
+			rule:  &config.AlertRule{Type: "threshold", Field: "Коробок", Op: ">", Value: 10, ClearTicks: 2},
+			ticks: []tick{present(11), present(5), absent, present(5)},
+			want:  []string{"fire", "", "", "resolve"},
+		},
+		{
+			name:  "clear value band above",
+			rule:  &config.AlertRule{Type: "threshold", Field: "Коробок", Op: ">", Value: 10, ClearValue: float(5)},
+			ticks: []tick{present(11), present(8), present(10), present(5), present(8), present(11)},
+			want:  []string{"fire", "", "", "resolve", "", "fire"},
+		},
+		{
+			name:  "clear value band below",
+			rule:  &config.AlertRule{Type: "threshold", Field: "Коробок", Op: "<", Value: 3, ClearValue: float(6)},
+			ticks: []tick{present(2), present(4), present(5), present(6)},
+			want:  []string{"fire", "", "", "resolve"},
+		},
+		{
+			name:  "missing key fires and resolves",
+			rule:  &config.AlertRule{Type: "missing"},
+			ticks: []tick{present(1), absent, absent, absent, present(1), present(1)},
+			want:  []string{"", "fire", "", "", "resolve", ""},
+		},
+		{
+			name:  "missing key for and clear ticks",
+			rule:  &config.AlertRule{Type: "missing", ForTicks: 2, ClearTicks: 2},
+			ticks: []tick{present(1), absent, present(1), absent, absent, present(1), absent, present(1), present(1)},
+			want:  []string{"", "", "", "", "fire", "", "", "", "resolve"},
+		},
+		{
+			name:  "missing key resolves after a long absence",
+			rule:  &config.AlertRule{Type: "missing"},
+			ticks: []tick{present(1), absent, absent, absent, absent, absent, absent, present(1)},
+			want:  []string{"", "fire", "", "", "", "", "", "resolve"},
+		},
+		{
+			name:  "unchanged value",
+			rule:  &config.AlertRule{Type: "unchanged", Field: "Коробок", Duration: 120000},
+			ticks: []tick{present(5), present(5), present(5), present(5), present(6), present(6)},
+			want:  []string{"", "", "fire", "", "resolve", ""},
+		},
+		{
+			name:  "unchanged value clears by a value kept changed",
+			rule:  &config.AlertRule{Type: "unchanged", Field: "Коробок", Duration: 120000, ClearTicks: 2},
+			ticks: []tick{present(5), present(5), present(5), present(6), present(6)},
+			want:  []string{"", "", "fire", "", "resolve"},
+		},
+	}
+
+	start := time.Date(2024, time.June, 3, 10, 0, 0, 0, time.UTC)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.rule.Name = "rule"
+			engine, err := NewEngineByConfig(&config.Alerts{Rules: []*config.AlertRule{tt.rule}}, "Парковка", testColumns)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for i, value := range tt.ticks {
+				// The key 2 keeps the report non-empty
+				s := &snapshot.Snapshot{Time: start.Add(time.Duration(i) * time.Minute), Rows: [][]int{{2, 0}}}
+				if value != nil {
+					s.Rows = append(s.Rows, []int{1, *value})
+				}
+
+				var got []string
+				for _, event := range engine.Evaluate(s) {
+					if event.Key != 1 {
+						continue
+					}
+					if event.Resolved {
+						got = append(got, "resolve")
+					} else {
+						got = append(got, "fire")
+					}
+				}
+
+				if strings.Join(got, ",") != tt.want[i] {
+					t.Errorf("tick %d: events %v, want %q", i, got, tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestNewRuleByConfigClearValue(t *testing.T) {
+	tests := []struct {
+		op         string
+		value      float64
+		clearValue float64
+		wantErr    bool
+	}{
+		{">", 10, 5, false},
+		{">", 10, 10, false},
+		{">", 10, 12, true},
+		{">=", 10, 11, true},
+		{"<", 3, 6, false},
+		{"<", 3, 1, true},
+		{"<=", 3, 2, true},
+		{"==", 3, 3, false},
+		{"==", 3, 4, true},
+		{"!=", 3, 4, true},
+	}
+
+	for _, tt := range tests {
+		cfg := &config.AlertRule{Name: "rule", Type: "threshold", Field: "Коробок", Op: tt.op, Value: tt.value, ClearValue: float(tt.clearValue)}
+		_, err := newRuleByConfig(cfg, testColumns)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s %g clear %g: error = %v, want error %v", tt.op, tt.value, tt.clearValue, err, tt.wantErr)
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package alerts
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"wb-assistance-logistic/logger"
+	"wb-assistance-logistic/telegramClient"
+)
+
+const (
+	FIRING_PREFIX   = "🔴 "
+	RESOLVED_PREFIX = "✅ "
+)
+
+type Notifier interface {
+	Notify(ctx context.Context, event *Event) error
+}
+
+// TelegramNotifier sends the alerts to the chats from the account of the telegram client
+type TelegramNotifier struct {
+	client *telegramClient.Client
+	chats  []int64
+	log    *slog.Logger
+}
+
+// NewTelegramNotifier resolves the chats given by an id or a public username, e.g. "-1001234567890" or "@logistic_alerts"
+func NewTelegramNotifier(client *telegramClient.Client, chats []string) (*TelegramNotifier, error) {
+	if client == nil {
+		return nil, errors.New("client can not be nil")
+	}
+
+	if len(chats) == 0 {
+		return nil, errors.New("chats can not be empty")
+	}
+
+	ids := make([]int64, 0, len(chats))
+	for _, chat := range chats {
+		id, err := strconv.ParseInt(chat, 10, 64)
+		if err != nil {
+			found, err := client.SearchPublicChat(strings.TrimPrefix(chat, "@"))
+			if err != nil {
+				return nil, fmt.Errorf("search alert chat %q: %w", chat, err)
+			}
+			id = found.Id
+		}
+
+		ids = append(ids, id)
+	}
+
+	return &TelegramNotifier{
+		client: client,
+		chats:  ids,
+		log:    logger.With("component", "alerts"),
+	}, nil
+}
+
+// Notify sends the event to every chat, a failed chat does not stop sending to the others
+func (n *TelegramNotifier) Notify(ctx context.Context, event *Event) error {
+	var errs []error
+
+	for _, chatID := range n.chats {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		n.log.DebugContext(ctx, "sending alert", "chat_id", chatID, "rule", event.Rule.Name)
+		_, err := n.client.SendMessageText(chatID, event.Text())
+		if err != nil {
+			errs = append(errs, fmt.Errorf("send alert to chat %d: %w", chatID, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// Text returns the message sent for the event
+func (e *Event) Text() string {
+	if e.Resolved {
+		return RESOLVED_PREFIX + e.Message
+	}
+
+	return FIRING_PREFIX + e.Message
+}
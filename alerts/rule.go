@@ -0,0 +1,132 @@
+package alerts
+
+import (
+	"errors"
+	"fmt"
+	"time"
+	"wb-assistance-logistic/config"
+	"wb-assistance-logistic/utils"
+)
+
+type RuleType string
+
+const (
+	THRESHOLD_RULE RuleType = "threshold" // The field compared with a value
+	UNCHANGED_RULE RuleType = "unchanged" // The field kept the same value for a duration
+	MISSING_RULE   RuleType = "missing"   // The key disappeared from the report
+	FORMAT_RULE    RuleType = "format"    // The bot reply format changed, raised by the parser and not configurable
+)
+
+// FormatRule is the rule of the format changed alerts
+var FormatRule = &Rule{Name: "format changed", Type: FORMAT_RULE, fieldIndex: -1}
+
+type Rule struct {
+	Name       string
+	Type       RuleType
+	Field      string
+	Key        *int // nil applies the rule to every key of the report
+	Op         string
+	Value      float64
+	ClearValue float64
+	Duration   time.Duration
+	ForTicks   int
+	ClearTicks int
+
+	fieldIndex int
+}
+
+func newRuleByConfig(cfg *config.AlertRule, columns []string) (*Rule, error) {
+	if cfg == nil {
+		return nil, errors.New("rule can not be nil")
+	}
+
+	if cfg.Name == "" {
+		return nil, errors.New("rule name can not be empty")
+	}
+
+	rule := &Rule{
+		Name:       cfg.Name,
+		Type:       RuleType(cfg.Type),
+		Field:      cfg.Field,
+		Key:        cfg.Key,
+		Op:         cfg.Op,
+		Value:      cfg.Value,
+		ClearValue: cfg.Value,
+		Duration:   time.Duration(cfg.Duration) * time.Millisecond,
+		ForTicks:   max(cfg.ForTicks, 1),
+		ClearTicks: max(cfg.ClearTicks, 1),
+		fieldIndex: -1,
+	}
+
+	if cfg.ClearValue != nil {
+		rule.ClearValue = *cfg.ClearValue
+	}
+
+	switch rule.Type {
+	case THRESHOLD_RULE:
+		if _, err := utils.Compare(0, rule.Op, 0); err != nil {
+			return nil, err
+		}
+
+		err := rule.validateClearValue()
+		if err != nil {
+			return nil, err
+		}
+	case UNCHANGED_RULE:
+		if rule.Duration <= 0 {
+			return nil, fmt.Errorf("rule %q: duration must be greater than zero", rule.Name)
+		}
+	case MISSING_RULE:
+		return rule, nil
+	default:
+		return nil, fmt.Errorf("rule %q: unknown type %q", rule.Name, cfg.Type)
+	}
+
+	for i, column := range columns {
+		if column == rule.Field {
+			rule.fieldIndex = i
+		}
+	}
+
+	if rule.fieldIndex < 0 {
+		return nil, fmt.Errorf("rule %q: unknown field %q", rule.Name, rule.Field)
+	}
+
+	return rule, nil
+}
+
+// validateClearValue checks the clear value is on the side of the value the alert clears to,
+// otherwise a single reading could both fire and clear the alert
+func (r *Rule) validateClearValue() error {
+	var isValid bool
+	switch r.Op {
+	case ">", ">=":
+		isValid = r.ClearValue <= r.Value
+	case "<", "<=":
+		isValid = r.ClearValue >= r.Value
+	default:
+		isValid = r.ClearValue == r.Value
+	}
+
+	if !isValid {
+		return fmt.Errorf("rule %q: clear value %g does not clear the condition %s %g", r.Name, r.ClearValue, r.Op, r.Value)
+	}
+
+	return nil
+}
+
+func (r *Rule) appliesTo(key int) bool {
+	return r.Key == nil || *r.Key == key
+}
+
+// triggered reports whether the value meets the firing condition
+func (r *Rule) triggered(value float64) bool {
+	ok, _ := utils.Compare(value, r.Op, r.Value)
+	return ok
+}
+
+// cleared reports whether the value is back behind the clear value, the gap between Value and ClearValue is the hysteresis
+func (r *Rule) cleared(value float64) bool {
+	ok, _ := utils.Compare(value, r.Op, r.ClearValue)
+	return !ok
+}
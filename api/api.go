@@ -0,0 +1,196 @@
+package api
+
+import (
+	"crypto/subtle"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"wb-assistance-logistic/httpServer"
+	"wb-assistance-logistic/logger"
+	"wb-assistance-logistic/snapshot"
+)
+
+const DEFAULT_TICKS_LIMIT = 50
+
+// API is a read-only REST API over the snapshot store, every endpoint requires the bearer token
+type API struct {
+	store *snapshot.Store
+	token string
+	log   *slog.Logger
+}
+
+func NewAPI(store *snapshot.Store, token string) (*API, error) {
+	if store == nil {
+		return nil, errors.New("snapshot store can not be nil")
+	}
+
+	if token == "" {
+		return nil, errors.New("api token can not be empty")
+	}
+
+	return &API{
+		store: store,
+		token: token,
+		log:   logger.With("component", "api"),
+	}, nil
+}
+
+func (a *API) Register(server *httpServer.Server) {
+	server.Handle("GET /api/v1/warehouses", a.auth(a.handleWarehouses))
+	server.Handle("GET /api/v1/warehouses/{warehouse}/snapshot", a.auth(a.handleSnapshot))
+	server.Handle("GET /api/v1/last-update", a.auth(a.handleLastUpdate))
+	server.Handle("GET /api/v1/ticks", a.auth(a.handleTicks))
+}
+
+func (a *API) auth(next http.HandlerFunc) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(a.token)) != 1 {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="api"`)
+			writeError(w, http.StatusUnauthorized, "invalid or missing bearer token")
+			return
+		}
+
+		next(w, r)
+	})
+}
+
+func (a *API) handleWarehouses(w http.ResponseWriter, _ *http.Request) {
+	type warehouse struct {
+		ID         string    `json:"id"`
+		LastUpdate time.Time `json:"last_update"`
+		Rows       int       `json:"rows"`
+	}
+
+	warehouses := []warehouse{}
+	for _, id := range a.store.Warehouses() {
+		if s, ok := a.store.Latest(id); ok {
+			warehouses = append(warehouses, warehouse{ID: id, LastUpdate: s.Time, Rows: len(s.Rows)})
+		}
+	}
+
+	writeJSON(w, http.StatusOK, warehouses)
+}
+
+// handleSnapshot serves the latest snapshot of the warehouse.
+// Query parameters: format=json|csv, key_from and key_to filter by the key column (parking number), sort=<column>, order=asc|desc.
+func (a *API) handleSnapshot(w http.ResponseWriter, r *http.Request) {
+	s, ok := a.store.Latest(r.PathValue("warehouse"))
+	if !ok {
+		writeError(w, http.StatusNotFound, "no snapshot for the warehouse")
+		return
+	}
+
+	query, err := parseQuery(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	result, err := s.Apply(query)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	switch format := r.URL.Query().Get("format"); format {
+	case "", "json":
+		writeJSON(w, http.StatusOK, result)
+	case "csv":
+		a.writeCSV(w, result)
+	default:
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("unknown format %q", format))
+	}
+}
+
+func (a *API) handleLastUpdate(w http.ResponseWriter, _ *http.Request) {
+	last := a.store.LastUpdate()
+
+	response := map[string]interface{}{"last_update": nil}
+	if !last.IsZero() {
+		response["last_update"] = last
+		response["age_seconds"] = int64(time.Since(last).Seconds())
+	}
+
+	writeJSON(w, http.StatusOK, response)
+}
+
+func (a *API) handleTicks(w http.ResponseWriter, r *http.Request) {
+	limit := DEFAULT_TICKS_LIMIT
+
+	if value := r.URL.Query().Get("limit"); value != "" {
+		var err error
+		limit, err = strconv.Atoi(value)
+		if err != nil || limit <= 0 {
+			writeError(w, http.StatusBadRequest, "limit must be a positive number")
+			return
+		}
+	}
+
+	writeJSON(w, http.StatusOK, a.store.History(limit))
+}
+
+func (a *API) writeCSV(w http.ResponseWriter, s *snapshot.Snapshot) {
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.csv"`, s.WarehouseID))
+
+	writer := csv.NewWriter(w)
+	_ = writer.Write(s.Columns)
+
+	record := make([]string, len(s.Columns))
+	for _, row := range s.Rows {
+		for i, value := range row {
+			record[i] = strconv.Itoa(value)
+		}
+		_ = writer.Write(record)
+	}
+
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		a.log.Warn("failed to write csv response", "error", err)
+	}
+}
+
+func parseQuery(r *http.Request) (snapshot.Query, error) {
+	values := r.URL.Query()
+	query := snapshot.Query{SortBy: values.Get("sort")}
+
+	for param, target := range map[string]**int{"key_from": &query.KeyFrom, "key_to": &query.KeyTo} {
+		value := values.Get(param)
+		if value == "" {
+			continue
+		}
+
+		number, err := strconv.Atoi(value)
+		if err != nil {
+			return query, fmt.Errorf("%s must be a number", param)
+		}
+		*target = &number
+	}
+
+	switch order := values.Get("order"); order {
+	case "", "asc":
+	case "desc":
+		query.Desc = true
+	default:
+		return query, fmt.Errorf("unknown order %q", order)
+	}
+
+	return query, nil
+}
+
+func writeJSON(w http.ResponseWriter, code int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, code int, message string) {
+	writeJSON(w, code, map[string]string{"error": message})
+}
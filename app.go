@@ -2,13 +2,25 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+	"wb-assistance-logistic/alerts"
+	"wb-assistance-logistic/api"
 	"wb-assistance-logistic/config"
+	"wb-assistance-logistic/health"
+	"wb-assistance-logistic/httpServer"
 	"wb-assistance-logistic/logger"
+	"wb-assistance-logistic/metrics"
 	"wb-assistance-logistic/parser"
 	"wb-assistance-logistic/sheets"
+	"wb-assistance-logistic/snapshot"
 	"wb-assistance-logistic/telegramClient"
 	"wb-assistance-logistic/timeTicker"
+	"wb-assistance-logistic/transform"
 	"wb-assistance-logistic/utils"
 )
 
@@ -18,101 +30,375 @@ type App struct {
 	parser         *parser.Parser
 	googleSheet    *sheets.Sheet
 	timeTicker     *timeTicker.TimeTicker
-
-	isStarted bool
+	httpServer     *httpServer.Server
+	health         *health.Checker
+	store          *snapshot.Store
+	alerts         *alerts.Engine
+	notifier       alerts.Notifier
+	transform      *transform.Transformer
+	log            *slog.Logger
+
+	// sheetRows is the number of rows the last tick wrote from the start cell, the ticks never overlap
+	sheetRows int
+
+	// mu guards the components created while the HTTP server is already serving
+	mu              sync.RWMutex
+	lastSuccessTick atomic.Int64
+	isStarted       atomic.Bool
 }
 
 func NewApp(cfg *config.Config) (*App, error) {
 	var err error
 	app := new(App)
 	app.config = cfg
-	app.isStarted = false
+	app.log = logger.With("component", "app")
 
-	app.timeTicker = timeTicker.NewTimeTicker(cfg.Ticker.Frequency)
+	app.timeTicker, err = timeTicker.NewTimeTickerByConfig(cfg.Ticker)
+	if err != nil {
+		return nil, fmt.Errorf("create ticker: %w", err)
+	}
 	app.timeTicker.SetCallback(app.tick)
+	app.timeTicker.SetReportHandler(app.onTickReport)
+
+	app.store, err = snapshot.NewStore(cfg.Storage.Path, cfg.Storage.HistorySize)
+	if err != nil {
+		return nil, fmt.Errorf("create snapshot store: %w", err)
+	}
+
+	// The HTTP server starts first so that the metrics are available while waiting for the authorization
+	if cfg.HTTP != nil && cfg.HTTP.Address != "" {
+		app.health = app.newHealthChecker()
+
+		app.httpServer = httpServer.NewServer(cfg.HTTP.Address)
+		app.httpServer.Handle("GET /metrics", metrics.Handler())
+		app.httpServer.Handle("GET /healthz", app.health.LiveHandler())
+		app.httpServer.Handle("GET /readyz", app.health.ReadyHandler())
+
+		if cfg.HTTP.APIToken != "" {
+			snapshotAPI, err := api.NewAPI(app.store, cfg.HTTP.APIToken)
+			if err != nil {
+				return nil, fmt.Errorf("create api: %w", err)
+			}
+			snapshotAPI.Register(app.httpServer)
+		} else {
+			app.log.Warn("http.api_token is not set, the snapshot api is disabled")
+		}
+
+		err = app.httpServer.Start()
+		if err != nil {
+			return nil, fmt.Errorf("start http server: %w", err)
+		}
+	}
 
-	logger.Init("App.NewApp()", "Telegram client")
-	//app.telegramClient = telegramClient.NewClient(int32(cfg.TelegramClient.Id), cfg.TelegramClient.Hash)
-	app.telegramClient, err = telegramClient.NewClientByParameters(&telegramClient.ClientParameters{
-		ApiId:   111,
-		ApiHash: "sd",
-	})
-	//
-	err = telegramClient.SetTelegramClientLogsVerboseLevel(telegramClient.LogsVerboseLevel(cfg.TelegramClient.LogLevel))
+	app.log.Info("initializing telegram client")
+	client, err := newTelegramClient(cfg)
 	if err != nil {
-		return nil, logger.Error("App.NewApp()", "Error set <Telegram client> logs verbose:\n", err)
+		return nil, err
 	}
-	logger.LogLn("App.NewApp()", "Setup logs verbose level <Telegram client>: ", cfg.TelegramClient.LogLevel)
+	client.SetStateHandler(app.onTelegramState)
+
+	app.mu.Lock()
+	app.telegramClient = client
+	app.mu.Unlock()
 
-	logger.Init("App.NewApp()", "Auth Telegram client")
+	app.log.Info("authorizing telegram client")
 	err = app.telegramClient.Auth()
 	if err != nil {
-		logger.LogLn("App.NewApp()", "Error auth <Telegram client>:\n", err)
+		app.log.Error("telegram client authorization failed", "error", err)
 	}
 
 	isAuth := <-app.telegramClient.AuthReady()
 	if !isAuth {
-		return nil, logger.Error("App.NewApp()", "Failed to auth <Telegram client>")
+		return nil, errors.New("telegram client is not authorized")
 	}
-	logger.InitSuccessfully("App.NewApp()", "Auth Telegram client")
-	logger.InitSuccessfully("App.NewApp()", "Telegram client")
+	app.log.Info("telegram client initialized")
 
-	logger.Init("App.NewApp()", "Parser")
+	app.log.Info("initializing parser")
 	app.parser, err = parser.NewParser(cfg.Parser, app.telegramClient)
 	if err != nil {
-		return nil, logger.Error("App.NewApp()", "Error create <Parser>:\n", err)
+		return nil, fmt.Errorf("create parser: %w", err)
+	}
+	app.log.Info("parser initialized")
+
+	// The chats alone receive the format changed alerts of the parser
+	if cfg.Alerts != nil && (len(cfg.Alerts.Rules) > 0 || len(cfg.Alerts.Chats) > 0) {
+		app.log.Info("initializing alerts")
+		if len(cfg.Alerts.Rules) > 0 {
+			app.alerts, err = alerts.NewEngineByConfig(cfg.Alerts, app.parser.MainKeyword(), app.parser.Columns())
+			if err != nil {
+				return nil, fmt.Errorf("create alerts: %w", err)
+			}
+		}
+
+		app.notifier, err = alerts.NewTelegramNotifier(app.telegramClient, cfg.Alerts.Chats)
+		if err != nil {
+			return nil, fmt.Errorf("create alerts notifier: %w", err)
+		}
+		app.log.Info("alerts initialized", "rules", len(cfg.Alerts.Rules), "chats", len(cfg.Alerts.Chats))
 	}
-	logger.InitSuccessfully("App.NewApp()", "Parser")
 
-	logger.Init("App.NewApp()", "Sheet")
-	logger.Init("App.NewApp()", "Sheet service")
+	if cfg.Transform != nil {
+		app.transform, err = transform.NewTransformerByConfig(cfg.Transform, app.parser.MainKeyword(), app.parser.Columns())
+		if err != nil {
+			return nil, fmt.Errorf("create transform: %w", err)
+		}
+		app.log.Info("transform initialized", "columns", len(cfg.Transform.Columns), "aggregates", len(cfg.Transform.Aggregates), "totals", cfg.Transform.Totals)
+	}
+
+	// The first tick after a restart blanks the rows of the last stored report too
+	if latest, ok := app.store.Latest(app.parser.WarehouseID()); ok {
+		app.sheetRows = len(latest.Rows)
+		if cfg.Transform != nil && cfg.Transform.Totals {
+			app.sheetRows++
+		}
+	}
+
+	app.log.Info("initializing sheet service")
 	googleService, err := CreateGoogleSheetsService(cfg.Sheets)
 	if err != nil {
-		return nil, logger.Error("App.NewApp()", "Error auth <Sheet> service:\n", err)
+		return nil, fmt.Errorf("authorize sheet service: %w", err)
 	}
-	logger.InitSuccessfully("App.NewApp()", "Sheet service")
 
-	app.googleSheet, err = sheets.NewSheetByService(cfg.Sheets.ID, googleService)
+	sheet, err := sheets.NewSheetByService(cfg.Sheets.ID, googleService)
 	if err != nil {
-		return nil, logger.Error("App.NewApp()", "Error initialization <Sheet> service\n", err)
+		return nil, fmt.Errorf("create sheet: %w", err)
 	}
-	logger.InitSuccessfully("App.NewApp()", "Sheet")
 
-	app.tick()
+	app.mu.Lock()
+	app.googleSheet = sheet
+	app.mu.Unlock()
+	app.log.Info("sheet service initialized", "sheet_id", cfg.Sheets.ID)
+
+	_ = app.tick(context.Background())
 
 	return app, nil
 }
 
-func (app *App) tick() {
-	logger.LogLn("App", "Parsing data...")
+func (app *App) tick(ctx context.Context) error {
+	tickID := logger.NewTickID()
+	ctx = logger.WithTickID(ctx, tickID)
+	start := time.Now()
+
+	rows, err := app.runTick(ctx, tickID)
+
+	duration := time.Since(start)
+
+	record := snapshot.TickRecord{
+		TickID:      tickID,
+		WarehouseID: app.parser.WarehouseID(),
+		Start:       start,
+		DurationMs:  duration.Milliseconds(),
+		Success:     err == nil,
+		Rows:        rows,
+	}
+	if err != nil {
+		record.Error = err.Error()
+	}
+
+	if storeErr := app.store.AddTick(record); storeErr != nil {
+		app.log.WarnContext(ctx, "failed to store tick record", "error", storeErr)
+	}
+
+	if err != nil {
+		// Failure streaks are summarized by the ticker, the per-tick details are only needed for debugging
+		app.log.DebugContext(ctx, "tick failed", "error", err, "duration", duration)
+		return err
+	}
+
+	app.lastSuccessTick.Store(time.Now().UnixNano())
+	app.log.InfoContext(ctx, "sheet updated", "rows", rows, "duration", duration)
+
+	return nil
+}
+
+func (app *App) onTickReport(report timeTicker.TickReport) {
+	metrics.TickDuration.Observe(report.Duration.Seconds())
+
+	if report.Canceled {
+		app.log.Warn("tick was canceled", "duration", report.Duration)
+	}
+}
+
+// runTick parses the report, stores the snapshot and writes it to the sheet, it returns the number of written rows
+func (app *App) runTick(ctx context.Context, tickID string) (int, error) {
+	app.log.InfoContext(ctx, "parsing data")
+
+	data, diagnostics, err := app.parser.Parse(ctx)
+	if diagnostics != nil {
+		app.log.InfoContext(ctx, "parse diagnostics", "diagnostics", diagnostics)
+
+		if diagnostics.DriftChanged {
+			app.notifyFormatDrift(ctx, diagnostics.Drift)
+		}
+	}
+	if err != nil {
+		return 0, fmt.Errorf("parse: %w", err)
+	}
+
+	app.log.DebugContext(ctx, "data parsed", "rows", len(data), "data", data)
+
+	snap := &snapshot.Snapshot{
+		TickID:      tickID,
+		WarehouseID: app.parser.WarehouseID(),
+		Time:        time.Now(),
+		KeyColumn:   app.parser.MainKeyword(),
+		Columns:     app.parser.Columns(),
+		Rows:        data,
+	}
+
+	err = app.store.Put(snap)
+	if err != nil {
+		app.log.WarnContext(ctx, "failed to store snapshot", "error", err)
+	}
+
+	app.evaluateAlerts(ctx, snap)
 
-	data, err := app.parser.Parse()
+	values := utils.ArrIntToInterface(data)
+	columns := len(snap.Columns)
+	var result *transform.Result
+	var summary [][]interface{}
+
+	if app.transform != nil {
+		result = app.transform.Apply(snap)
+		values, summary, columns = result.Rows, result.Summary, len(result.Columns)
+	}
+
+	// Blank rows overwrite the rows left by a longer previous report, e.g. its old totals row
+	err = app.updateSheet(ctx, app.config.Sheets.StartIndex, padRows(values, app.sheetRows, columns))
 	if err != nil {
-		logger.Warning("App.NewApp()", "Error parse:\n", err)
+		return 0, fmt.Errorf("update sheet: %w", err)
+	}
+	app.sheetRows = len(values)
+
+	if result != nil {
+		app.transform.Commit(result)
+	}
+
+	if summary != nil {
+		err = app.updateSheet(ctx, app.transform.SummaryAnchor(), summary)
+		if err != nil {
+			return 0, fmt.Errorf("update summary: %w", err)
+		}
+	}
+
+	return len(data), nil
+}
+
+// padRows appends the rows of empty cells up to the given number of rows
+func padRows(data [][]interface{}, rows int, columns int) [][]interface{} {
+	for len(data) < rows {
+		row := make([]interface{}, columns)
+		for i := range row {
+			row[i] = ""
+		}
+		data = append(data, row)
+	}
+
+	return data
+}
+
+// evaluateAlerts sends the alerts changed by the snapshot, a failed notification does not fail the tick
+func (app *App) evaluateAlerts(ctx context.Context, snap *snapshot.Snapshot) {
+	if app.alerts == nil {
 		return
 	}
 
-	logger.LogLn("App", "Data: ", data)
+	for _, event := range app.alerts.Evaluate(snap) {
+		app.notify(ctx, event)
+	}
+}
 
-	err = app.googleSheet.Update(app.config.Sheets.Name, app.config.Sheets.StartIndex, utils.ArrIntToInterface(data))
+// notifyFormatDrift alerts the bot reply format change, or its end when the drift is nil
+func (app *App) notifyFormatDrift(ctx context.Context, drift *parser.Drift) {
+	if app.notifier == nil {
+		return
+	}
+
+	event := alerts.NewFormatEvent(true, "format changed resolved: the reply matches the learned format again", time.Now())
+	if drift != nil {
+		event = alerts.NewFormatEvent(false, drift.Message(), time.Now())
+	}
+
+	app.notify(ctx, event)
+}
+
+// notify sends the alert event, a failed notification does not fail the tick
+func (app *App) notify(ctx context.Context, event *alerts.Event) {
+	state := metrics.ALERT_FIRING
+	if event.Resolved {
+		state = metrics.ALERT_RESOLVED
+	}
+	metrics.AlertEvents.WithLabelValues(event.Rule.Name, state).Inc()
+
+	app.log.InfoContext(ctx, "alert "+state, "rule", event.Rule.Name, "key", event.Key, "message", event.Message)
+
+	err := app.notifier.Notify(ctx, event)
 	if err != nil {
-		logger.Warning("App", "Error append data to Sheet:\n", err)
+		app.log.WarnContext(ctx, "failed to notify alert", "rule", event.Rule.Name, "error", err)
+	}
+}
+
+// updateSheet writes the data to the sheet from the start cell retrying failed attempts according to the sheets config
+func (app *App) updateSheet(ctx context.Context, startIndex string, data [][]interface{}) error {
+	cfg := app.config.Sheets
+	delay := time.Duration(cfg.WriteRetryDelay) * time.Millisecond
+
+	var err error
+	for attempt := 0; attempt <= cfg.WriteRetries; attempt++ {
+		if attempt > 0 {
+			metrics.SheetsWriteRetries.Inc()
+			app.log.WarnContext(ctx, "retrying sheet update", "attempt", attempt, "error", err)
+
+			select {
+			case <-time.After(delay):
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+
+		start := time.Now()
+		err = app.googleSheet.Update(cfg.Name, startIndex, data)
+		metrics.SheetsWriteDuration.WithLabelValues(metrics.SHEETS_OP_UPDATE).ObserveDuration(start)
+		if err == nil {
+			metrics.MarkSheetsWrite()
+			return nil
+		}
+	}
+
+	metrics.SheetsWriteTotal.WithLabelValues(metrics.RESULT_FAILURE).Inc()
+
+	return err
+}
+
+// onTelegramState pauses the ticks while the telegram session is not authorized and resumes them after the reconnect
+func (app *App) onTelegramState(state telegramClient.AuthState) {
+	if state == telegramClient.AUTH_STATE_READY {
+		if app.isStarted.Load() && !app.timeTicker.IsStarted() {
+			app.log.Info("telegram session is ready, resuming ticks")
+			app.timeTicker.Start()
+		}
 		return
 	}
 
-	logger.LogLn("App", "Sheet was update")
+	if app.timeTicker.IsStarted() {
+		app.log.Warn("telegram session is not ready, pausing ticks", "state", state)
+		app.timeTicker.Stop()
+	}
 }
 
 func (app *App) Start() {
-	logger.LogLn("App", "Starting app...")
-	app.timeTicker.Start()
-	app.isStarted = true
+	app.log.Info("starting app", "next_tick", app.timeTicker.NextRun())
+	app.isStarted.Store(true)
+
+	if app.telegramClient.IsAuth() {
+		app.timeTicker.Start()
+	}
 }
 
 func (app *App) Stop() {
-	logger.LogLn("App", "Stopping app...")
+	app.log.Info("stopping app")
+	app.isStarted.Store(false)
 	app.timeTicker.Stop()
-	app.isStarted = false
 }
 
 func CreateGoogleSheetsService(cfg *config.Sheets) (sheets.ServiceInterface, error) {
@@ -127,16 +413,16 @@ func AuthGoogleSheetsClient(tokenCredentials, clientCredentials string) (*sheets
 	client, err := AuthGoogleSheetsClientByToken(tokenCredentials, clientCredentials)
 
 	if err != nil {
-		logger.Warning("App.AuthGoogleSheetsClient()", err)
+		logger.Warn("google sheets token authorization failed", "error", err)
 
 		client, err = AuthGoogleSheetClientByCredentials(clientCredentials)
 		if err != nil {
-			return nil, logger.Error("App.AuthGoogleSheetsClient()", "Failed to login in Google Sheet serviceCredentials using client tokenCredentials file and credentials file:\n", err)
+			return nil, fmt.Errorf("authorize google sheets client by token and credentials: %w", err)
 		}
 
 		err = client.SaveJSONToken(tokenCredentials)
 		if err != nil {
-			logger.Warning("App.AuthGoogleSheetsClient()", "Failed to save the received tokenCredentials to a file\n", err)
+			logger.Warn("failed to save google sheets token", "path", tokenCredentials, "error", err)
 		}
 	}
 
@@ -144,20 +430,20 @@ func AuthGoogleSheetsClient(tokenCredentials, clientCredentials string) (*sheets
 }
 
 func AuthGoogleSheetsClientByToken(token string, client string) (*sheets.Client, error) {
-	logger.LogLn("App.AuthGoogleSheetsClientByToken()", "Start auth Google Sheet internal by file token: "+token)
+	logger.Info("authorizing google sheets client by token", "path", token)
 
 	service := sheets.NewClient()
 
 	err := service.AuthByJSONTokenAutoRefresh(context.Background(), token, client, sheets.SHEETS_ALL_SCOPE)
 	if err != nil {
-		return nil, logger.Error("App.AuthGoogleSheetsClientByToken()", "Error auth Google Sheet internal by file token:\n", err)
+		return nil, fmt.Errorf("authorize google sheets client by token: %w", err)
 	}
 
 	return service, nil
 }
 
 func AuthGoogleSheetClientByCredentials(clientCredentials string) (*sheets.Client, error) {
-	logger.LogLn("App.AuthGoogleSheetClientByCredentials()", "Start auth Google Sheet internal by file internal credentials: "+clientCredentials)
+	logger.Info("authorizing google sheets client by credentials", "path", clientCredentials)
 
 	service := sheets.NewClient()
 
@@ -166,14 +452,14 @@ func AuthGoogleSheetClientByCredentials(clientCredentials string) (*sheets.Clien
 		clientCredentials,
 		sheets.SHEETS_ALL_SCOPE,
 		func(authURL string) string {
-			logger.LogLn("App.AuthGoogleSheetClientByCredentials()", "Auth URL: "+authURL)
+			logger.Info("open the google auth url and enter the code", "url", authURL)
 			var code string
 
 			for {
-				logger.Log("App.AuthGoogleSheetClientByCredentials()", "Auth code: ")
+				fmt.Print("Auth code: ")
 				_, err := fmt.Scanln(&code)
 				if err != nil {
-					logger.Warning("App.AuthGoogleSheetClientByCredentials()", "Error reading code\n", err)
+					logger.Warn("failed to read google auth code", "error", err)
 					continue
 				}
 				break
@@ -183,19 +469,19 @@ func AuthGoogleSheetClientByCredentials(clientCredentials string) (*sheets.Clien
 		})
 
 	if err != nil {
-		return nil, logger.Error("App.AuthGoogleSheetClientByCredentials()", "Error auth Google Sheet internal by file credentials:\n", err)
+		return nil, fmt.Errorf("authorize google sheets client by credentials: %w", err)
 	}
 
 	return service, nil
 }
 
 func AuthGoogleSheetsService(serviceCredentials string) (*sheets.Service, error) {
-	logger.LogLn("App.AuthGoogleSheetsService()", "Start auth GoogleSheets internal by file internal credentials: "+serviceCredentials)
+	logger.Info("authorizing google sheets service account", "path", serviceCredentials)
 
 	service := sheets.NewService()
 	err := service.Auth(context.Background(), serviceCredentials, sheets.SHEETS_ALL_SCOPE)
 	if err != nil {
-		return nil, logger.Error("App.AuthGoogleSheetsService()", "Failed to login in Google Sheet internal using internal credentials file:\n", err)
+		return nil, fmt.Errorf("authorize google sheets service account: %w", err)
 	}
 
 	return service, nil
@@ -2,18 +2,70 @@ package config
 
 import (
 	"encoding/json"
+	"fmt"
 	"os"
 	"wb-assistance-logistic/logger"
 )
 
+type TickerWindow struct {
+	Days      []string `json:"days"`
+	From      string   `json:"from"`
+	To        string   `json:"to"`
+	Frequency int      `json:"frequency"`
+}
+
+type TickerBackoff struct {
+	Initial    int     `json:"initial"`
+	Max        int     `json:"max"`
+	Multiplier float64 `json:"multiplier"`
+}
+
 type TimeTicker struct {
-	Frequency int `json:"frequency"`
+	Frequency     int             `json:"frequency"`
+	Timezone      string          `json:"timezone"`
+	Cron          []string        `json:"cron"`
+	Windows       []*TickerWindow `json:"windows"`
+	Holidays      []string        `json:"holidays"`
+	OverlapPolicy string          `json:"overlap_policy"`
+	Jitter        int             `json:"jitter"`
+	Backoff       *TickerBackoff  `json:"backoff"`
+}
+
+type TelegramAuth struct {
+	Mode          string `json:"mode"`
+	QRCodeFile    string `json:"qr_code_file"`
+	Handler       string `json:"handler"`
+	PhoneNumber   string `json:"phone_number"`
+	Password      string `json:"password"`
+	DropDirectory string `json:"drop_directory"`
+	HTTPAddress   string `json:"http_address"`
+}
+
+type TelegramRateLimit struct {
+	ChatInterval int `json:"chat_interval"`
+	ReadRetries  int `json:"read_retries"`
+	MaxRetryWait int `json:"max_retry_wait"`
+}
+
+type TelegramProxy struct {
+	Type     string `json:"type"`
+	Server   string `json:"server"`
+	Port     int    `json:"port"`
+	Username string `json:"username"`
+	Password string `json:"password"`
+	HttpOnly bool   `json:"http_only"`
+	Secret   string `json:"secret"`
 }
 
 type TelegramClient struct {
-	Id       int    `json:"id"`
-	Hash     string `json:"hash"`
-	LogLevel int    `json:"log_level"`
+	Id                 int                `json:"id"`
+	Hash               string             `json:"hash"`
+	LogLevel           int                `json:"log_level"`
+	FilesDirectory     string             `json:"files_directory"`
+	Auth               *TelegramAuth      `json:"auth"`
+	RateLimit          *TelegramRateLimit `json:"rate_limit"`
+	Proxies            []*TelegramProxy   `json:"proxies"`
+	ProxyCheckInterval int                `json:"proxy_check_interval"`
 }
 
 type Sheets struct {
@@ -22,10 +74,12 @@ type Sheets struct {
 		ClientToken string `json:"client_token"`
 		Service     string `json:"service"`
 	} `json:"credentials"`
-	ID           string `json:"id"`
-	Name         string `json:"name"`
-	StartIndex   string `json:"start_index"`
-	IsClientAuth bool   `json:"client_auth"`
+	ID              string `json:"id"`
+	Name            string `json:"name"`
+	StartIndex      string `json:"start_index"`
+	IsClientAuth    bool   `json:"client_auth"`
+	WriteRetries    int    `json:"write_retries"`
+	WriteRetryDelay int    `json:"write_retry_delay"`
 }
 
 type Parser struct {
@@ -41,6 +95,52 @@ type Parser struct {
 	SortKeyword        string   `json:"sort_keyword"`
 	IsSort             bool     `json:"sort"`
 	IsSortInvert       bool     `json:"sort_invert"`
+	// Sort keys applied in order, they replace the sort keyword and the sort flags when set
+	SortBy []*SortKey `json:"sort_by"`
+
+	// Mode is "line" (default), "block" or "table"
+	Mode string `json:"mode"`
+	// Text starting a record in the block mode, the main keyword when empty
+	RecordStart string `json:"record_start"`
+	// Header names of the keywords in the documents and the text tables, the keyword itself when not set
+	DocumentColumns map[string]string `json:"document_columns"`
+	// What to do with the rows of the same main keyword value: "newest" (default), "sum", "max" or "error"
+	DuplicatePolicy string `json:"duplicate_policy"`
+	// Condition the parsed rows must meet in addition to the key values
+	Filter *RowFilter `json:"filter"`
+	// Detection of the bot reply format changes, nil disables it
+	FormatDrift *FormatDrift `json:"format_drift"`
+
+	// Milliseconds without edits of the reply before it is parsed, zero parses right after the request time sleep
+	EditQuietPeriod int `json:"edit_quiet_period"`
+	// Milliseconds to wait for the reply to settle, the current version is parsed after it
+	ReplyTimeout int `json:"reply_timeout"`
+}
+
+type FormatDrift struct {
+	LearnTicks   int     `json:"learn_ticks"`   // Replies learned before the detection starts
+	Threshold    float64 `json:"threshold"`     // Share of the reply lines of an unknown shape making a drift
+	BaselineFile string  `json:"baseline_file"` // Keeps the learned format between the restarts when set, delete it to learn a new format
+}
+
+type SortKey struct {
+	Field string `json:"field"`
+	Desc  bool   `json:"desc"`
+}
+
+// RowFilter is a condition on the parsed row, every node sets exactly one of the conditions.
+// Field is a keyword, the main keyword when empty. From and To are inclusive, either can be omitted.
+type RowFilter struct {
+	Field string       `json:"field"`
+	Op    string       `json:"op"`
+	Value *int         `json:"value"`
+	In    []int        `json:"in"`
+	NotIn []int        `json:"not_in"`
+	From  *int         `json:"from"`
+	To    *int         `json:"to"`
+	All   []*RowFilter `json:"all"`
+	Any   []*RowFilter `json:"any"`
+	Not   *RowFilter   `json:"not"`
 }
 
 type Control struct {
@@ -49,30 +149,120 @@ type Control struct {
 	IsDebugLog    bool   `json:"debug_log"`
 }
 
+type LogOutput struct {
+	Type      string `json:"type"`
+	Format    string `json:"format"`
+	Level     string `json:"level"`
+	Path      string `json:"path"`
+	MaxSizeMB int    `json:"max_size_mb"`
+	Daily     bool   `json:"daily"`
+	MaxFiles  int    `json:"max_files"`
+	Compress  bool   `json:"compress"`
+}
+
+type Log struct {
+	Level   string       `json:"level"`
+	Format  string       `json:"format"`
+	Outputs []*LogOutput `json:"outputs"`
+}
+
+type HTTP struct {
+	Address    string `json:"address"`
+	MaxTickAge int    `json:"max_tick_age"`
+	APIToken   string `json:"api_token"`
+}
+
+type Storage struct {
+	Path        string `json:"path"`
+	HistorySize int    `json:"history_size"`
+}
+
+type AlertRule struct {
+	Name       string   `json:"name"`
+	Type       string   `json:"type"`
+	Field      string   `json:"field"`
+	Key        *int     `json:"key"`
+	Op         string   `json:"op"`
+	Value      float64  `json:"value"`
+	ClearValue *float64 `json:"clear_value"`
+	Duration   int      `json:"duration"`
+	ForTicks   int      `json:"for_ticks"`
+	ClearTicks int      `json:"clear_ticks"`
+}
+
+type Alerts struct {
+	Chats []string     `json:"chats"`
+	Rules []*AlertRule `json:"rules"`
+}
+
+// TransformColumn is a column computed for every row and written after the parsed columns
+type TransformColumn struct {
+	Name      string `json:"name"`
+	Type      string `json:"type"`
+	Field     string `json:"field"`
+	By        string `json:"by"`        // Divisor of the ratio columns
+	Precision *int   `json:"precision"` // Decimal places of the ratio columns
+}
+
+// TransformAggregate is a value computed over all the rows and written to the summary block
+type TransformAggregate struct {
+	Name  string   `json:"name"`
+	Type  string   `json:"type"`
+	Field string   `json:"field"`
+	Op    string   `json:"op"` // Condition of the counted rows, every row is counted when empty
+	Value *float64 `json:"value"`
+}
+
+type Transform struct {
+	Columns       []*TransformColumn    `json:"columns"`
+	Aggregates    []*TransformAggregate `json:"aggregates"`
+	Totals        bool                  `json:"totals"`
+	TotalsLabel   string                `json:"totals_label"`
+	SummaryAnchor string                `json:"summary_anchor"`
+}
+
 type Config struct {
+	Log            *Log            `json:"log"`
 	Ticker         *TimeTicker     `json:"ticker"`
 	TelegramClient *TelegramClient `json:"telegram_client"`
 	Sheets         *Sheets         `json:"sheets"`
 	Parser         *Parser         `json:"parser"`
 	Control        *Control        `json:"control"`
+	HTTP           *HTTP           `json:"http"`
+	Storage        *Storage        `json:"storage"`
+	Alerts         *Alerts         `json:"alerts"`
+	Transform      *Transform      `json:"transform"`
 }
 
 var config *Config = new(Config)
 
 func Init(path string) error {
-	logger.Init("Config.Init()", "Configuration")
+	logger.Info("loading configuration", "path", path)
 
 	file, err := os.ReadFile(path)
 	if err != nil {
-		return logger.LogError("Config.Init()", "Error reading file configuration: ", err)
+		return fmt.Errorf("read configuration file: %w", err)
 	}
 
 	err = json.Unmarshal(file, config)
 	if err != nil {
-		return logger.LogError("Config.Init()", "Error unmarshalling file configuration: ", err)
+		return fmt.Errorf("unmarshal configuration file: %w", err)
+	}
+
+	if config.Log == nil {
+		config.Log = &Log{}
+	}
+
+	if config.Storage == nil {
+		config.Storage = &Storage{}
+	}
+
+	err = config.Validate()
+	if err != nil {
+		return fmt.Errorf("validate configuration: %w", err)
 	}
 
-	logger.InitSuccessfully("Config.Init()", "Configuration")
+	logger.Info("configuration loaded", "path", path)
 	return nil
 }
 
@@ -0,0 +1,68 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+)
+
+// Validate checks the sections which can be checked without the services they configure
+func (c *Config) Validate() error {
+	if c.Parser != nil {
+		err := c.Parser.Validate()
+		if err != nil {
+			return fmt.Errorf("parser: %w", err)
+		}
+	}
+
+	return nil
+}
+
+func (p *Parser) Validate() error {
+	validationErrors := map[string]bool{
+		"invalid chat username":                       p.ChatUsername == "",
+		"invalid count read messages":                 p.CountReadMessages == 0,
+		"invalid count skip lines":                    p.SkipLines < 0,
+		"invalid warehouse id":                        len(p.WarehouseID) <= 4,
+		"invalid warehouse main keyword":              p.MainKeyword == "",
+		"invalid warehouse command request warehouse": p.CommandRequestData == "",
+		"invalid warehouse keywords":                  p.Keywords == nil,
+		"invalid warehouse key values":                p.KeyValues == nil && p.Filter == nil,
+		"invalid sort keyword":                        p.IsSort && p.SortBy == nil && p.SortKeyword == "",
+		"invalid request time sleep":                  p.RequestTimeSleep < 500,
+		"invalid edit quiet period":                   p.EditQuietPeriod < 0,
+		"invalid reply timeout":                       p.ReplyTimeout < 0,
+	}
+
+	for msg, invalid := range validationErrors {
+		if invalid {
+			return errors.New(msg)
+		}
+	}
+
+	// The parsed rows are ordered as the keywords, so every sorted field must be one of them
+	for _, key := range p.SortKeys() {
+		if key == nil {
+			return errors.New("sort key can not be nil")
+		}
+
+		if key.Field == "" || !slices.Contains(p.Keywords, key.Field) {
+			return fmt.Errorf("unknown sort field %q", key.Field)
+		}
+	}
+
+	return nil
+}
+
+// SortKeys returns the sort spec, the legacy sort keyword and flags are converted to a single key
+func (p *Parser) SortKeys() []*SortKey {
+	if p.SortBy != nil {
+		return p.SortBy
+	}
+
+	if !p.IsSort {
+		return nil
+	}
+
+	return []*SortKey{{Field: p.SortKeyword, Desc: p.IsSortInvert}}
+}
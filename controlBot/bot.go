@@ -0,0 +1,163 @@
+package controlBot
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	API_URL = "https://api.telegram.org/bot%s/%s"
+
+	// Extra time for the HTTP request over the long polling timeout
+	requestTimeoutMargin = 10 * time.Second
+)
+
+type User struct {
+	ID       int64  `json:"id"`
+	Username string `json:"username"`
+}
+
+type Chat struct {
+	ID int64 `json:"id"`
+}
+
+type Message struct {
+	MessageID int64  `json:"message_id"`
+	From      *User  `json:"from"`
+	Chat      *Chat  `json:"chat"`
+	Text      string `json:"text"`
+}
+
+type Update struct {
+	UpdateID int64    `json:"update_id"`
+	Message  *Message `json:"message"`
+}
+
+// APIError is an error returned by the Bot API
+type APIError struct {
+	Code        int
+	Description string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("bot api error %d: %s", e.Code, e.Description)
+}
+
+type response struct {
+	Ok          bool            `json:"ok"`
+	Result      json.RawMessage `json:"result"`
+	ErrorCode   int             `json:"error_code"`
+	Description string          `json:"description"`
+}
+
+// Bot is a minimal Telegram Bot API client receiving the updates by long polling
+type Bot struct {
+	token  string
+	client *http.Client
+	offset int64
+}
+
+func NewBot(token string) (*Bot, error) {
+	if token == "" {
+		return nil, errors.New("bot token can not be empty")
+	}
+
+	return &Bot{
+		token:  token,
+		client: &http.Client{},
+	}, nil
+}
+
+func (b *Bot) GetMe(ctx context.Context) (*User, error) {
+	user := new(User)
+	err := b.call(ctx, "getMe", url.Values{}, user, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+func (b *Bot) SendMessage(ctx context.Context, chatID int64, text string) (*Message, error) {
+	params := url.Values{
+		"chat_id": {strconv.FormatInt(chatID, 10)},
+		"text":    {text},
+	}
+
+	message := new(Message)
+	err := b.call(ctx, "sendMessage", params, message, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	return message, nil
+}
+
+// GetUpdates waits up to the timeout for the updates following the already received ones
+func (b *Bot) GetUpdates(ctx context.Context, timeout time.Duration) ([]*Update, error) {
+	params := url.Values{
+		"offset":          {strconv.FormatInt(b.offset, 10)},
+		"timeout":         {strconv.Itoa(int(timeout.Seconds()))},
+		"allowed_updates": {`["message"]`},
+	}
+
+	var updates []*Update
+	err := b.call(ctx, "getUpdates", params, &updates, timeout)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, update := range updates {
+		if update.UpdateID >= b.offset {
+			b.offset = update.UpdateID + 1
+		}
+	}
+
+	return updates, nil
+}
+
+func (b *Bot) call(ctx context.Context, method string, params url.Values, result any, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout+requestTimeoutMargin)
+	defer cancel()
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, fmt.Sprintf(API_URL, b.token, method), strings.NewReader(params.Encode()))
+	if err != nil {
+		return fmt.Errorf("create %s request: %w", method, err)
+	}
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	httpResponse, err := b.client.Do(request)
+	if err != nil {
+		// The url contains the token, so the error is reported without it
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		return fmt.Errorf("call %s: %w", method, err)
+	}
+	defer httpResponse.Body.Close()
+
+	var resp response
+	err = json.NewDecoder(httpResponse.Body).Decode(&resp)
+	if err != nil {
+		return fmt.Errorf("decode %s response: %w", method, err)
+	}
+
+	if !resp.Ok {
+		return &APIError{Code: resp.ErrorCode, Description: resp.Description}
+	}
+
+	err = json.Unmarshal(resp.Result, result)
+	if err != nil {
+		return fmt.Errorf("decode %s result: %w", method, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"wb-assistance-logistic/config"
+	"wb-assistance-logistic/logger"
+	"wb-assistance-logistic/parser"
+)
+
+// runDiagnostics requests the report once and writes what the parser did with every line of the reply
+func runDiagnostics(ctx context.Context, cfg *config.Config, w io.Writer) error {
+	client, err := newTelegramClient(cfg)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	err = client.Auth()
+	if err != nil {
+		logger.Error("telegram client authorization failed", "error", err)
+	}
+
+	if !<-client.AuthReady() {
+		return errors.New("telegram client is not authorized")
+	}
+
+	p, err := parser.NewParser(cfg.Parser, client)
+	if err != nil {
+		return fmt.Errorf("create parser: %w", err)
+	}
+	// A single reply must not change the baseline the service learns from
+	p.SetReadOnly(true)
+
+	_, diagnostics, parseErr := p.Parse(ctx)
+	if diagnostics != nil {
+		err = diagnostics.WriteReport(w)
+		if err != nil {
+			return fmt.Errorf("write diagnostics: %w", err)
+		}
+	}
+
+	return parseErr
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"time"
+	"wb-assistance-logistic/health"
+)
+
+const defaultMaxTickAgeFactor = 10
+
+func (app *App) newHealthChecker() *health.Checker {
+	checker := health.NewChecker()
+
+	checker.Add("telegram", func() error {
+		app.mu.RLock()
+		defer app.mu.RUnlock()
+
+		if app.telegramClient == nil {
+			return errors.New("not created")
+		}
+		if !app.telegramClient.IsAuth() {
+			return fmt.Errorf("not authorized, session state %q", app.telegramClient.State())
+		}
+		return nil
+	})
+
+	checker.Add("sheets", func() error {
+		app.mu.RLock()
+		defer app.mu.RUnlock()
+
+		if app.googleSheet == nil {
+			return errors.New("not created")
+		}
+		if !app.googleSheet.IsAuth() {
+			return errors.New("not authorized")
+		}
+		return nil
+	})
+
+	checker.Add("ticker", func() error {
+		if !app.timeTicker.IsStarted() {
+			return errors.New("not started")
+		}
+		return nil
+	})
+
+	checker.Add("last_tick", func() error {
+		last := app.lastSuccessTick.Load()
+		if last == 0 {
+			return errors.New("no successful tick yet")
+		}
+
+		age := time.Since(time.Unix(0, last))
+		if maxAge := app.maxTickAge(); maxAge > 0 && age > maxAge {
+			return fmt.Errorf("last successful tick %s ago, max %s", age.Round(time.Second), maxAge)
+		}
+		return nil
+	})
+
+	return checker
+}
+
+// maxTickAge is the configured readiness limit or ten ticker periods when it is not set, zero disables the limit
+func (app *App) maxTickAge() time.Duration {
+	if app.config.HTTP != nil && app.config.HTTP.MaxTickAge > 0 {
+		return time.Duration(app.config.HTTP.MaxTickAge) * time.Millisecond
+	}
+
+	return time.Duration(app.config.Ticker.Frequency*defaultMaxTickAgeFactor) * time.Millisecond
+}
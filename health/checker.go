@@ -0,0 +1,111 @@
+package health
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+)
+
+type Status string
+
+const (
+	STATUS_OK        Status = "ok"
+	STATUS_FAIL      Status = "fail"
+	STATUS_READY     Status = "ready"
+	STATUS_NOT_READY Status = "not_ready"
+)
+
+// Check returns nil when the component is ready, the error text is reported as the component message
+type Check func() error
+
+type ComponentStatus struct {
+	Status  Status `json:"status"`
+	Message string `json:"message,omitempty"`
+}
+
+type Report struct {
+	Status     Status                     `json:"status"`
+	Time       time.Time                  `json:"time"`
+	Components map[string]ComponentStatus `json:"components"`
+}
+
+type Checker struct {
+	mu     sync.RWMutex
+	names  []string
+	checks map[string]Check
+	start  time.Time
+}
+
+func NewChecker() *Checker {
+	return &Checker{
+		checks: map[string]Check{},
+		start:  time.Now(),
+	}
+}
+
+func (c *Checker) Add(name string, check Check) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, ok := c.checks[name]; !ok {
+		c.names = append(c.names, name)
+	}
+	c.checks[name] = check
+}
+
+func (c *Checker) Report() Report {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	report := Report{
+		Status:     STATUS_READY,
+		Time:       time.Now(),
+		Components: make(map[string]ComponentStatus, len(c.names)),
+	}
+
+	for _, name := range c.names {
+		status := ComponentStatus{Status: STATUS_OK}
+
+		err := c.checks[name]()
+		if err != nil {
+			status = ComponentStatus{Status: STATUS_FAIL, Message: err.Error()}
+			report.Status = STATUS_NOT_READY
+		}
+
+		report.Components[name] = status
+	}
+
+	return report
+}
+
+// ReadyHandler responds with the report, the status code is 503 when any component is not ready
+func (c *Checker) ReadyHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		report := c.Report()
+
+		code := http.StatusOK
+		if report.Status != STATUS_READY {
+			code = http.StatusServiceUnavailable
+		}
+
+		writeJSON(w, code, report)
+	})
+}
+
+// LiveHandler reports that the process is able to serve requests
+func (c *Checker) LiveHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"status":         STATUS_OK,
+			"uptime_seconds": int64(time.Since(c.start).Seconds()),
+		})
+	})
+}
+
+func writeJSON(w http.ResponseWriter, code int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,66 @@
+package httpServer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"time"
+	"wb-assistance-logistic/logger"
+)
+
+type Server struct {
+	address string
+	mux     *http.ServeMux
+	server  *http.Server
+	log     *slog.Logger
+}
+
+func NewServer(address string) *Server {
+	mux := http.NewServeMux()
+
+	return &Server{
+		address: address,
+		mux:     mux,
+		server: &http.Server{
+			Addr:              address,
+			Handler:           mux,
+			ReadHeaderTimeout: 10 * time.Second,
+		},
+		log: logger.With("component", "http_server"),
+	}
+}
+
+// Handle registers the handler for the pattern, patterns use the http.ServeMux syntax, e.g. "GET /metrics"
+func (s *Server) Handle(pattern string, handler http.Handler) {
+	s.mux.Handle(pattern, handler)
+}
+
+func (s *Server) HandleFunc(pattern string, handler func(http.ResponseWriter, *http.Request)) {
+	s.mux.HandleFunc(pattern, handler)
+}
+
+// Start binds the address and serves in the background, a listen error is returned immediately
+func (s *Server) Start() error {
+	listener, err := net.Listen("tcp", s.address)
+	if err != nil {
+		return fmt.Errorf("listen %s: %w", s.address, err)
+	}
+
+	s.log.Info("http server started", "address", listener.Addr().String())
+
+	go func() {
+		err := s.server.Serve(listener)
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			s.log.Error("http server stopped", "error", err)
+		}
+	}()
+
+	return nil
+}
+
+func (s *Server) Stop(ctx context.Context) error {
+	return s.server.Shutdown(ctx)
+}
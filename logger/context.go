@@ -0,0 +1,64 @@
+package logger
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"log/slog"
+)
+
+const TICK_ID_KEY = "tick_id"
+
+type tickIDKey struct{}
+
+func NewTickID() string {
+	b := make([]byte, 6)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+func WithTickID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, tickIDKey{}, id)
+}
+
+func TickID(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+
+	id, _ := ctx.Value(tickIDKey{}).(string)
+	return id
+}
+
+// ContextHandler adds the tick id stored in the record context to every record
+type ContextHandler struct {
+	handler slog.Handler
+}
+
+func NewContextHandler(handler slog.Handler) *ContextHandler {
+	if h, ok := handler.(*ContextHandler); ok {
+		return h
+	}
+
+	return &ContextHandler{handler: handler}
+}
+
+func (h *ContextHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.handler.Enabled(ctx, level)
+}
+
+func (h *ContextHandler) Handle(ctx context.Context, record slog.Record) error {
+	if id := TickID(ctx); id != "" {
+		record.AddAttrs(slog.String(TICK_ID_KEY, id))
+	}
+
+	return h.handler.Handle(ctx, record)
+}
+
+func (h *ContextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &ContextHandler{handler: h.handler.WithAttrs(attrs)}
+}
+
+func (h *ContextHandler) WithGroup(name string) slog.Handler {
+	return &ContextHandler{handler: h.handler.WithGroup(name)}
+}
@@ -1,41 +1,203 @@
 package logger
 
 import (
+	"context"
 	"errors"
 	"fmt"
-	"log"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
 )
 
-func Init(place string, target string) {
-	log.Print("[INIT]   ", place+" >> ", "Start initialization <"+target+">")
+type Format string
+
+const (
+	TEXT_FORMAT Format = "text"
+	JSON_FORMAT Format = "json"
+)
+
+type OutputType string
+
+const (
+	CONSOLE_OUTPUT OutputType = "console"
+	FILE_OUTPUT    OutputType = "file"
+)
+
+type Output struct {
+	Type   OutputType
+	Format Format
+	Level  slog.Level
+	Rotate RotateOptions // Used by the file output only
+}
+
+type Options struct {
+	Outputs []Output
+}
+
+var (
+	current atomic.Pointer[slog.Logger]
+
+	filesMu sync.Mutex
+	files   []*RotatingFile
+)
+
+func init() {
+	current.Store(slog.New(NewContextHandler(slog.NewTextHandler(os.Stderr, nil))))
+}
+
+// Setup replaces the application logger and closes the files opened by the previous Setup.
+// Loggers obtained through With before the call keep the old handler, so Setup is expected to run before the components are created.
+func Setup(opts Options) error {
+	if len(opts.Outputs) == 0 {
+		opts.Outputs = []Output{{Type: CONSOLE_OUTPUT, Level: slog.LevelInfo}}
+	}
+
+	var handlers []slog.Handler
+	var opened []*RotatingFile
+
+	closeOpened := func() {
+		for _, file := range opened {
+			_ = file.Close()
+		}
+	}
+
+	for _, output := range opts.Outputs {
+		var w io.Writer
+
+		switch output.Type {
+		case CONSOLE_OUTPUT, "":
+			w = os.Stderr
+		case FILE_OUTPUT:
+			file, err := NewRotatingFile(output.Rotate)
+			if err != nil {
+				closeOpened()
+				return err
+			}
+			opened = append(opened, file)
+			w = file
+		default:
+			closeOpened()
+			return fmt.Errorf("unknown log output %q", output.Type)
+		}
+
+		handler, err := NewHandler(output.Format, w, output.Level)
+		if err != nil {
+			closeOpened()
+			return err
+		}
+		handlers = append(handlers, handler)
+	}
+
+	if len(handlers) == 1 {
+		SetHandler(handlers[0])
+	} else {
+		SetHandler(NewMultiHandler(handlers...))
+	}
+
+	filesMu.Lock()
+	previous := files
+	files = opened
+	filesMu.Unlock()
+
+	for _, file := range previous {
+		_ = file.Close()
+	}
+
+	return nil
+}
+
+// Close closes the log files, records logged afterwards to a file output are dropped
+func Close() error {
+	filesMu.Lock()
+	defer filesMu.Unlock()
+
+	var errs []error
+	for _, file := range files {
+		errs = append(errs, file.Close())
+	}
+	files = nil
+
+	return errors.Join(errs...)
+}
+
+// SetHandler wraps the handler so that tick ids from the context are attached and installs it as the application and slog default logger.
+func SetHandler(handler slog.Handler) {
+	logger := slog.New(NewContextHandler(handler))
+	current.Store(logger)
+	slog.SetDefault(logger)
+}
+
+func NewHandler(format Format, w io.Writer, level slog.Leveler) (slog.Handler, error) {
+	opts := &slog.HandlerOptions{Level: level}
+
+	switch format {
+	case TEXT_FORMAT, "":
+		return slog.NewTextHandler(w, opts), nil
+	case JSON_FORMAT:
+		return slog.NewJSONHandler(w, opts), nil
+	}
+
+	return nil, fmt.Errorf("unknown log format %q", format)
+}
+
+func ParseLevel(level string) (slog.Level, error) {
+	var l slog.Level
+	if level == "" {
+		return slog.LevelInfo, nil
+	}
+
+	err := l.UnmarshalText([]byte(strings.ToUpper(level)))
+	if err != nil {
+		return slog.LevelInfo, fmt.Errorf("unknown log level %q", level)
+	}
+
+	return l, nil
+}
+
+func Get() *slog.Logger {
+	return current.Load()
+}
+
+func With(args ...any) *slog.Logger {
+	return Get().With(args...)
+}
+
+func Debug(msg string, args ...any) {
+	Get().Debug(msg, args...)
+}
+
+func Info(msg string, args ...any) {
+	Get().Info(msg, args...)
 }
 
-func InitSuccessfully(place string, target string) {
-	log.Print("[INIT]   ", place+" >> ", "Initialization <"+target+"> successfully")
+func Warn(msg string, args ...any) {
+	Get().Warn(msg, args...)
 }
 
-func Log(place string, msg ...interface{}) {
-	log.Print("[INFO]", place+" >> ", fmt.Sprint(msg...))
+func Error(msg string, args ...any) {
+	Get().Error(msg, args...)
 }
 
-func LogLn(place string, msg ...interface{}) {
-	log.Println("[INFO]", place+" >> ", fmt.Sprint(msg...))
+func DebugContext(ctx context.Context, msg string, args ...any) {
+	Get().DebugContext(ctx, msg, args...)
 }
 
-func Warning(place string, msg ...interface{}) {
-	log.Println("[WARNING] ", place+" >> ", fmt.Sprint(msg...))
+func InfoContext(ctx context.Context, msg string, args ...any) {
+	Get().InfoContext(ctx, msg, args...)
 }
 
-func Fatal(place string, msg ...interface{}) {
-	log.Fatalln("[FATAL] ", place+" >> ", fmt.Sprint(msg...))
+func WarnContext(ctx context.Context, msg string, args ...any) {
+	Get().WarnContext(ctx, msg, args...)
 }
 
-func LogError(place string, msg ...interface{}) error {
-	message := fmt.Sprintln("[ERROR] ", place+" >> ", fmt.Sprint(msg...))
-	log.Println(message)
-	return errors.New(message)
+func ErrorContext(ctx context.Context, msg string, args ...any) {
+	Get().ErrorContext(ctx, msg, args...)
 }
 
-func Error(place string, msg ...interface{}) error {
-	return errors.New(fmt.Sprintln("[ERROR] ", place+" >> ", fmt.Sprint(msg...)))
+func Fatal(msg string, args ...any) {
+	Get().Error(msg, args...)
+	os.Exit(1)
 }
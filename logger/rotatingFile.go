@@ -0,0 +1,262 @@
+package logger
+
+import (
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const rotatedTimeLayout = "2006-01-02T15-04-05.000"
+
+type RotateOptions struct {
+	Path     string
+	MaxSize  int64 // Size in bytes after which the file is rotated, 0 disables rotation by size
+	Daily    bool
+	MaxFiles int // Number of rotated files to keep, 0 keeps all of them
+	Compress bool
+}
+
+// RotatingFile is an io.Writer appending to a file which is rotated by size and/or by day.
+// Rotated files are renamed to <path>.<timestamp> and optionally gzipped.
+type RotatingFile struct {
+	opts     RotateOptions
+	mu       sync.Mutex
+	cleanMu  sync.Mutex
+	file     *os.File
+	size     int64
+	openedAt time.Time
+	now      func() time.Time
+}
+
+func NewRotatingFile(opts RotateOptions) (*RotatingFile, error) {
+	if opts.Path == "" {
+		return nil, errors.New("log file path can not be empty")
+	}
+
+	if opts.MaxSize < 0 || opts.MaxFiles < 0 {
+		return nil, errors.New("log file max size and max files can not be negative")
+	}
+
+	f := &RotatingFile{
+		opts: opts,
+		now:  time.Now,
+	}
+
+	err := f.open()
+	if err != nil {
+		return nil, err
+	}
+
+	return f, nil
+}
+
+func (f *RotatingFile) Write(p []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.file == nil {
+		return 0, os.ErrClosed
+	}
+
+	if f.needRotate(int64(len(p))) {
+		err := f.rotate()
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	n, err := f.file.Write(p)
+	f.size += int64(n)
+
+	return n, err
+}
+
+func (f *RotatingFile) Rotate() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.file == nil {
+		return os.ErrClosed
+	}
+
+	return f.rotate()
+}
+
+func (f *RotatingFile) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.file == nil {
+		return nil
+	}
+
+	err := f.file.Close()
+	f.file = nil
+
+	return err
+}
+
+func (f *RotatingFile) needRotate(size int64) bool {
+	if f.opts.MaxSize > 0 && f.size > 0 && f.size+size > f.opts.MaxSize {
+		return true
+	}
+
+	return f.opts.Daily && f.isNewDay()
+}
+
+func (f *RotatingFile) isNewDay() bool {
+	y1, m1, d1 := f.openedAt.Date()
+	y2, m2, d2 := f.now().Date()
+	return y1 != y2 || m1 != m2 || d1 != d2
+}
+
+func (f *RotatingFile) open() error {
+	err := os.MkdirAll(filepath.Dir(f.opts.Path), 0o755)
+	if err != nil {
+		return fmt.Errorf("create log directory: %w", err)
+	}
+
+	file, err := os.OpenFile(f.opts.Path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return fmt.Errorf("open log file: %w", err)
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		_ = file.Close()
+		return fmt.Errorf("stat log file: %w", err)
+	}
+
+	f.file = file
+	f.size = info.Size()
+	f.openedAt = f.now()
+	if f.size > 0 {
+		// An existing file keeps the day it was last written, so a restart on the next day still rotates it
+		f.openedAt = info.ModTime()
+	}
+
+	return nil
+}
+
+func (f *RotatingFile) rotate() error {
+	// A daily file is stamped with the last moment of the day its content covers
+	stamp := f.now()
+	if f.opts.Daily && f.isNewDay() {
+		y, m, d := f.openedAt.Date()
+		stamp = time.Date(y, m, d+1, 0, 0, 0, 0, f.openedAt.Location()).Add(-time.Millisecond)
+	}
+
+	err := f.file.Close()
+	if err != nil {
+		return fmt.Errorf("close log file: %w", err)
+	}
+	f.file = nil
+
+	rotated := f.opts.Path + "." + stamp.Format(rotatedTimeLayout)
+	renameErr := os.Rename(f.opts.Path, rotated)
+	if errors.Is(renameErr, os.ErrNotExist) {
+		renameErr = nil
+	}
+
+	err = f.open()
+	if err != nil {
+		return err
+	}
+
+	if renameErr != nil {
+		// Keep writing to the same file, the next rotation is tried after another day or another max size of data
+		f.openedAt = f.now()
+		f.size = 0
+		go Warn("failed to rotate log file", "path", f.opts.Path, "error", renameErr)
+		return nil
+	}
+
+	go f.postRotate(rotated)
+
+	return nil
+}
+
+// postRotate compresses the rotated file and removes the old ones, it runs outside of the write lock
+func (f *RotatingFile) postRotate(rotated string) {
+	f.cleanMu.Lock()
+	defer f.cleanMu.Unlock()
+
+	if f.opts.Compress {
+		err := compressFile(rotated)
+		if err != nil {
+			Warn("failed to compress rotated log file", "path", rotated, "error", err)
+		}
+	}
+
+	if f.opts.MaxFiles > 0 {
+		err := f.removeOld()
+		if err != nil {
+			Warn("failed to remove old log files", "path", f.opts.Path, "error", err)
+		}
+	}
+}
+
+func (f *RotatingFile) removeOld() error {
+	matches, err := filepath.Glob(f.opts.Path + ".*")
+	if err != nil {
+		return err
+	}
+
+	var rotated []string
+	for _, match := range matches {
+		stamp := strings.TrimSuffix(strings.TrimPrefix(match, f.opts.Path+"."), ".gz")
+		if _, err := time.Parse(rotatedTimeLayout, stamp); err == nil {
+			rotated = append(rotated, match)
+		}
+	}
+
+	if len(rotated) <= f.opts.MaxFiles {
+		return nil
+	}
+
+	// The timestamp layout sorts lexically in chronological order
+	sort.Strings(rotated)
+
+	var errs []error
+	for _, path := range rotated[:len(rotated)-f.opts.MaxFiles] {
+		errs = append(errs, os.Remove(path))
+	}
+
+	return errors.Join(errs...)
+}
+
+func compressFile(path string) error {
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	dst, err := os.OpenFile(path+".gz", os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o644)
+	if err != nil {
+		return err
+	}
+
+	gz := gzip.NewWriter(dst)
+	_, err = io.Copy(gz, src)
+	if err == nil {
+		err = gz.Close()
+	}
+	if closeErr := dst.Close(); err == nil {
+		err = closeErr
+	}
+
+	if err != nil {
+		_ = os.Remove(path + ".gz")
+		return err
+	}
+
+	return os.Remove(path)
+}
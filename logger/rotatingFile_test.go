@@ -0,0 +1,127 @@
+package logger
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+type testClock struct {
+	t time.Time
+}
+
+func (c *testClock) now() time.Time {
+	return c.t
+}
+
+func newTestRotatingFile(t *testing.T, opts RotateOptions, clock *testClock) *RotatingFile {
+	t.Helper()
+
+	f := &RotatingFile{opts: opts, now: clock.now}
+	if err := f.open(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = f.Close() })
+
+	return f
+}
+
+func write(t *testing.T, f *RotatingFile, text string) {
+	t.Helper()
+
+	if _, err := f.Write([]byte(text)); err != nil {
+		t.Fatalf("write %q: %v", text, err)
+	}
+}
+
+func assertContent(t *testing.T, path, want string) {
+	t.Helper()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != want {
+		t.Errorf("%s contains %q, want %q", filepath.Base(path), data, want)
+	}
+}
+
+func TestRotatingFileBySize(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	clock := &testClock{t: time.Date(2024, time.June, 3, 10, 0, 0, 0, time.UTC)}
+	f := newTestRotatingFile(t, RotateOptions{Path: path, MaxSize: 10}, clock)
+
+	write(t, f, "first\n")
+	write(t, f, "abc\n")
+
+	clock.t = clock.t.Add(time.Second)
+	write(t, f, "second\n")
+
+	assertContent(t, path+".2024-06-03T10-00-01.000", "first\nabc\n")
+	assertContent(t, path, "second\n")
+}
+
+func TestRotatingFileDaily(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	clock := &testClock{t: time.Date(2024, time.June, 3, 23, 58, 0, 0, time.UTC)}
+	f := newTestRotatingFile(t, RotateOptions{Path: path, Daily: true}, clock)
+
+	write(t, f, "monday\n")
+
+	clock.t = clock.t.Add(time.Minute)
+	write(t, f, "still monday\n")
+
+	// The archive is stamped with the day that ended, not with the day of the rotation
+	clock.t = time.Date(2024, time.June, 4, 0, 1, 0, 0, time.UTC)
+	write(t, f, "tuesday\n")
+
+	assertContent(t, path+".2024-06-03T23-59-59.999", "monday\nstill monday\n")
+	assertContent(t, path, "tuesday\n")
+}
+
+func TestRotatingFileFailedRename(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	clock := &testClock{t: time.Date(2024, time.June, 3, 12, 0, 0, 0, time.UTC)}
+	f := newTestRotatingFile(t, RotateOptions{Path: path, Daily: true}, clock)
+
+	write(t, f, "monday\n")
+
+	// A non-empty directory in place of the archive makes the rename fail
+	archive := path + ".2024-06-03T23-59-59.999"
+	if err := os.MkdirAll(filepath.Join(archive, "taken"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	clock.t = time.Date(2024, time.June, 4, 0, 1, 0, 0, time.UTC)
+	write(t, f, "tuesday\n")
+
+	// The next rotation is tried on the next day only
+	clock.t = clock.t.Add(time.Hour)
+	write(t, f, "later\n")
+
+	assertContent(t, path, "monday\ntuesday\nlater\n")
+	if info, err := os.Stat(archive); err != nil || !info.IsDir() {
+		t.Errorf("archive directory changed: %v", err)
+	}
+
+	clock.t = time.Date(2024, time.June, 5, 0, 1, 0, 0, time.UTC)
+	write(t, f, "wednesday\n")
+
+	assertContent(t, path+".2024-06-04T23-59-59.999", "monday\ntuesday\nlater\n")
+	assertContent(t, path, "wednesday\n")
+}
+
+func TestRotatingFileClosed(t *testing.T) {
+	f, err := NewRotatingFile(RotateOptions{Path: filepath.Join(t.TempDir(), "app.log")})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.Write([]byte("late\n")); err == nil {
+		t.Error("write to a closed file returned no error")
+	}
+}
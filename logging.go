@@ -0,0 +1,67 @@
+package main
+
+import (
+	"log/slog"
+	"wb-assistance-logistic/config"
+	"wb-assistance-logistic/logger"
+)
+
+func setupLogger(cfg *config.Log, control *config.Control) error {
+	if cfg == nil {
+		return nil
+	}
+
+	level, err := logger.ParseLevel(cfg.Level)
+	if err != nil {
+		return err
+	}
+
+	outputs := cfg.Outputs
+	if len(outputs) == 0 {
+		outputs = []*config.LogOutput{{Type: string(logger.CONSOLE_OUTPUT)}}
+	}
+
+	opts := logger.Options{}
+	for _, out := range outputs {
+		output := logger.Output{
+			Type:   logger.OutputType(out.Type),
+			Format: logger.Format(cfg.Format),
+			Level:  level,
+		}
+
+		if out.Format != "" {
+			output.Format = logger.Format(out.Format)
+		}
+
+		if out.Level != "" {
+			output.Level, err = logger.ParseLevel(out.Level)
+			if err != nil {
+				return err
+			}
+		}
+
+		if output.Type == logger.FILE_OUTPUT {
+			// An output with an explicit level keeps it, otherwise the debug lines
+			// reach the log files only when the debug log is enabled
+			if out.Level == "" {
+				if control != nil && control.IsDebugLog {
+					output.Level = slog.LevelDebug
+				} else if output.Level < slog.LevelInfo {
+					output.Level = slog.LevelInfo
+				}
+			}
+
+			output.Rotate = logger.RotateOptions{
+				Path:     out.Path,
+				MaxSize:  int64(out.MaxSizeMB) * 1024 * 1024,
+				Daily:    out.Daily,
+				MaxFiles: out.MaxFiles,
+				Compress: out.Compress,
+			}
+		}
+
+		opts.Outputs = append(opts.Outputs, output)
+	}
+
+	return logger.Setup(opts)
+}
@@ -1,27 +1,51 @@
 package main
 
 import (
+	"context"
+	"flag"
 	"os"
+	"os/signal"
 	"wb-assistance-logistic/config"
 	"wb-assistance-logistic/logger"
 )
 
 func main() {
+	isDiagnostics := flag.Bool("diagnostics", false, "request the report once, print the parse diagnostics of every line and exit")
+	flag.Parse()
+
 	defer func() {
 		if err := recover(); err != nil {
-			logger.LogLn("Main()", "Recover error: \n", err)
+			logger.Error("recovered from panic", "panic", err)
 			<-make(chan os.Signal, 1)
 		}
 	}()
 
 	err := config.Init(".cfg")
 	if err != nil {
-		_ = logger.LogError("Main()", "Error initialization configuration:\n", err)
+		logger.Error("failed to initialize configuration", "error", err)
+	}
+
+	err = setupLogger(config.Get().Log, config.Get().Control)
+	if err != nil {
+		logger.Error("failed to set up logger", "error", err)
+	}
+
+	if *isDiagnostics {
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer stop()
+
+		err = runDiagnostics(ctx, config.Get(), os.Stdout)
+		if err != nil {
+			logger.Error("diagnostics failed", "error", err)
+			stop()
+			os.Exit(1)
+		}
+		return
 	}
 
 	app, err := NewApp(config.Get())
 	if err != nil {
-		_ = logger.LogError("Main()", "Error initializing application:\n", err)
+		logger.Error("failed to initialize application", "error", err)
 	} else if app != nil {
 		app.Start()
 	}
@@ -0,0 +1,90 @@
+package metrics
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// Parse failure reasons used as the "reason" label of ParseTotal
+const (
+	REASON_REQUEST   = "request"
+	REASON_MESSAGES  = "messages"
+	REASON_EMPTY     = "no_messages"
+	REASON_SKIP      = "skip_limit"
+	REASON_SORT      = "sort"
+	REASON_FLOOD     = "flood_wait"
+	REASON_DOCUMENT  = "document"
+	REASON_DUPLICATE = "duplicate"
+	REASON_REPLY     = "reply"
+)
+
+const (
+	RESULT_SUCCESS = "success"
+	RESULT_FAILURE = "failure"
+)
+
+const SHEETS_OP_UPDATE = "update"
+
+// Alert states used as the "state" label of AlertEvents
+const (
+	ALERT_FIRING   = "firing"
+	ALERT_RESOLVED = "resolved"
+)
+
+var (
+	TickDuration = NewHistogram("wb_tick_duration_seconds",
+		"Duration of a whole tick: request, parse and sheet write.", nil)
+
+	ParseTotal = NewCounter("wb_parse_total",
+		"Parse attempts by result and failure reason.", "result", "reason")
+
+	ParsedRows = NewCounter("wb_parsed_rows_total",
+		"Rows accepted by the parser.")
+
+	SkippedLines = NewCounter("wb_parser_skipped_lines_total",
+		"Lines skipped by the parser because the keywords could not be extracted.")
+
+	TelegramRequestDuration = NewHistogram("wb_telegram_request_duration_seconds",
+		"Latency of TDLib requests by method.", nil, "method")
+
+	TelegramFloodWaits = NewCounter("wb_telegram_flood_waits_total",
+		"TDLib requests answered with a flood wait by method.", "method")
+
+	SheetsWriteDuration = NewHistogram("wb_sheets_write_duration_seconds",
+		"Latency of a single Google Sheets write attempt.", nil, "operation")
+
+	SheetsWriteRetries = NewCounter("wb_sheets_write_retries_total",
+		"Google Sheets writes retried after a failed attempt.")
+
+	SheetsWriteTotal = NewCounter("wb_sheets_write_total",
+		"Google Sheets writes by final result.", "result")
+
+	// Alert on a stale sheet with: time() - wb_sheets_last_success_timestamp_seconds > N * 60
+	SheetsLastSuccess = NewGauge("wb_sheets_last_success_timestamp_seconds",
+		"Unix time of the last successful Google Sheets write.")
+
+	AlertEvents = NewCounter("wb_alert_events_total",
+		"Alerts fired and resolved by rule.", "rule", "state")
+
+	_ = NewGaugeFunc("wb_sheets_seconds_since_last_success",
+		"Seconds since the last successful Google Sheets write, -1 before the first write.", secondsSinceLastWrite)
+)
+
+var lastWrite atomic.Int64
+
+// MarkSheetsWrite records a successful sheet write
+func MarkSheetsWrite() {
+	now := time.Now()
+	lastWrite.Store(now.UnixNano())
+	SheetsLastSuccess.Set(float64(now.UnixNano()) / 1e9)
+	SheetsWriteTotal.WithLabelValues(RESULT_SUCCESS).Inc()
+}
+
+func secondsSinceLastWrite() float64 {
+	last := lastWrite.Load()
+	if last == 0 {
+		return -1
+	}
+
+	return time.Since(time.Unix(0, last)).Seconds()
+}
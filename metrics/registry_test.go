@@ -0,0 +1,81 @@
+package metrics
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRegistryWrite(t *testing.T) {
+	r := NewRegistry()
+
+	counter := &Counter{family: newFamily("test_requests_total", "Requests by method.\nSecond line with a \\.", COUNTER_TYPE, []string{"method", "path"})}
+	r.register(counter.family.name, counter.family)
+
+	gauge := &Gauge{family: newFamily("test_temperature", "Current temperature.", GAUGE_TYPE, nil)}
+	gauge.family.get(nil, nil)
+	r.register(gauge.family.name, gauge.family)
+
+	r.register("test_uptime_seconds", &GaugeFunc{name: "test_uptime_seconds", help: "Uptime.", fn: func() float64 { return 42 }})
+
+	histogram := &Histogram{family: newFamily("test_duration_seconds", "Request duration.", HISTOGRAM_TYPE, []string{"op"}), buckets: []float64{0.1, 0.5, 1}}
+	r.register(histogram.family.name, histogram.family)
+
+	counter.WithLabelValues("get", "/a").Inc()
+	counter.WithLabelValues("get", "/a").Add(2)
+	counter.WithLabelValues("post", "tab\there \"quoted\" back\\slash\nnew line юникод").Inc()
+	counter.WithLabelValues("get", "/a").Add(-5)
+
+	gauge.Set(-1.5)
+
+	for _, v := range []float64{0.05, 0.1, 0.3, 2} {
+		histogram.WithLabelValues("read").Observe(v)
+	}
+	histogram.WithLabelValues("write").Observe(0.7)
+
+	want := `# HELP test_requests_total Requests by method.\nSecond line with a \\.
+# TYPE test_requests_total counter
+test_requests_total{method="get",path="/a"} 3
+test_requests_total{method="post",path="tab	here \"quoted\" back\\slash\nnew line юникод"} 1
+# HELP test_temperature Current temperature.
+# TYPE test_temperature gauge
+test_temperature -1.5
+# HELP test_uptime_seconds Uptime.
+# TYPE test_uptime_seconds gauge
+test_uptime_seconds 42
+# HELP test_duration_seconds Request duration.
+# TYPE test_duration_seconds histogram
+test_duration_seconds_bucket{op="read",le="0.1"} 2
+test_duration_seconds_bucket{op="read",le="0.5"} 3
+test_duration_seconds_bucket{op="read",le="1"} 3
+test_duration_seconds_bucket{op="read",le="+Inf"} 4
+test_duration_seconds_sum{op="read"} 2.45
+test_duration_seconds_count{op="read"} 4
+test_duration_seconds_bucket{op="write",le="0.1"} 0
+test_duration_seconds_bucket{op="write",le="0.5"} 0
+test_duration_seconds_bucket{op="write",le="1"} 1
+test_duration_seconds_bucket{op="write",le="+Inf"} 1
+test_duration_seconds_sum{op="write"} 0.7
+test_duration_seconds_count{op="write"} 1
+`
+
+	var out strings.Builder
+	if err := r.Write(&out); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := out.String(); got != want {
+		t.Errorf("unexpected exposition:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestRegistryDuplicate(t *testing.T) {
+	r := NewRegistry()
+	r.register("test_total", newFamily("test_total", "", COUNTER_TYPE, nil))
+
+	defer func() {
+		if recover() == nil {
+			t.Error("duplicate metric did not panic")
+		}
+	}()
+	r.register("test_total", newFamily("test_total", "", COUNTER_TYPE, nil))
+}
@@ -0,0 +1,174 @@
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"time"
+)
+
+type Counter struct {
+	family *family
+}
+
+type CounterSeries struct {
+	family *family
+	series *series
+}
+
+func NewCounter(name, help string, labels ...string) *Counter {
+	c := &Counter{family: newFamily(name, help, COUNTER_TYPE, labels)}
+	if len(labels) == 0 {
+		c.family.get(nil, nil)
+	}
+	defaultRegistry.register(name, c.family)
+	return c
+}
+
+func (c *Counter) WithLabelValues(values ...string) *CounterSeries {
+	return &CounterSeries{family: c.family, series: c.family.get(values, nil)}
+}
+
+func (c *Counter) Inc() {
+	c.WithLabelValues().Inc()
+}
+
+func (c *Counter) Add(v float64) {
+	c.WithLabelValues().Add(v)
+}
+
+func (s *CounterSeries) Inc() {
+	s.Add(1)
+}
+
+// Add increases the counter, negative values are ignored because counters never go down
+func (s *CounterSeries) Add(v float64) {
+	if v < 0 {
+		return
+	}
+
+	s.family.mu.Lock()
+	s.series.value += v
+	s.family.mu.Unlock()
+}
+
+type Gauge struct {
+	family *family
+}
+
+type GaugeSeries struct {
+	family *family
+	series *series
+}
+
+func NewGauge(name, help string, labels ...string) *Gauge {
+	g := &Gauge{family: newFamily(name, help, GAUGE_TYPE, labels)}
+	if len(labels) == 0 {
+		g.family.get(nil, nil)
+	}
+	defaultRegistry.register(name, g.family)
+	return g
+}
+
+func (g *Gauge) WithLabelValues(values ...string) *GaugeSeries {
+	return &GaugeSeries{family: g.family, series: g.family.get(values, nil)}
+}
+
+func (g *Gauge) Set(v float64) {
+	g.WithLabelValues().Set(v)
+}
+
+func (g *Gauge) SetToCurrentTime() {
+	g.WithLabelValues().SetToCurrentTime()
+}
+
+func (s *GaugeSeries) Set(v float64) {
+	s.family.mu.Lock()
+	s.series.value = v
+	s.family.mu.Unlock()
+}
+
+func (s *GaugeSeries) Add(v float64) {
+	s.family.mu.Lock()
+	s.series.value += v
+	s.family.mu.Unlock()
+}
+
+func (s *GaugeSeries) SetToCurrentTime() {
+	s.Set(float64(time.Now().UnixNano()) / 1e9)
+}
+
+// GaugeFunc is a gauge without labels whose value is computed on every scrape
+type GaugeFunc struct {
+	name string
+	help string
+	fn   func() float64
+}
+
+func NewGaugeFunc(name, help string, fn func() float64) *GaugeFunc {
+	g := &GaugeFunc{name: name, help: help, fn: fn}
+	defaultRegistry.register(name, g)
+	return g
+}
+
+func (g *GaugeFunc) write(w io.Writer) {
+	fmt.Fprintf(w, "# HELP %s %s\n", g.name, escapeHelp(g.help))
+	fmt.Fprintf(w, "# TYPE %s %s\n", g.name, GAUGE_TYPE)
+	fmt.Fprintf(w, "%s %s\n", g.name, formatFloat(g.fn()))
+}
+
+type Histogram struct {
+	family  *family
+	buckets []float64
+}
+
+type HistogramSeries struct {
+	family *family
+	series *series
+}
+
+func NewHistogram(name, help string, buckets []float64, labels ...string) *Histogram {
+	if len(buckets) == 0 {
+		buckets = DEFAULT_BUCKETS
+	}
+
+	buckets = append([]float64(nil), buckets...)
+	sort.Float64s(buckets)
+
+	h := &Histogram{family: newFamily(name, help, HISTOGRAM_TYPE, labels), buckets: buckets}
+	if len(labels) == 0 {
+		h.family.get(nil, buckets)
+	}
+	defaultRegistry.register(name, h.family)
+	return h
+}
+
+func (h *Histogram) WithLabelValues(values ...string) *HistogramSeries {
+	return &HistogramSeries{family: h.family, series: h.family.get(values, h.buckets)}
+}
+
+func (h *Histogram) Observe(v float64) {
+	h.WithLabelValues().Observe(v)
+}
+
+func (h *Histogram) ObserveDuration(start time.Time) {
+	h.Observe(time.Since(start).Seconds())
+}
+
+func (s *HistogramSeries) Observe(v float64) {
+	s.family.mu.Lock()
+	defer s.family.mu.Unlock()
+
+	for i, bound := range s.series.buckets {
+		if v <= bound {
+			s.series.counts[i]++
+			break
+		}
+	}
+	s.series.count++
+	s.series.value += v
+}
+
+func (s *HistogramSeries) ObserveDuration(start time.Time) {
+	s.Observe(time.Since(start).Seconds())
+}
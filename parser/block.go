@@ -0,0 +1,34 @@
+package parser
+
+import (
+	"context"
+	"strings"
+)
+
+// parseBlocks reads the records spread over several lines, e.g. a "Парковка 12" line followed by "ШК: 40" and "Коробок: 7".
+// A record starts at a line containing the record start marker and lasts until the next one, the lines before the first record are ignored.
+func (p *Parser) parseBlocks(ctx context.Context, text string) ([][]int, error) {
+	var (
+		records []string
+		current []string
+	)
+
+	for _, line := range strings.Split(text, "\n") {
+		if strings.Contains(line, p.recordStart) {
+			if current != nil {
+				records = append(records, strings.Join(current, "\n"))
+			}
+			current = []string{}
+		}
+
+		if current != nil {
+			current = append(current, line)
+		}
+	}
+
+	if current != nil {
+		records = append(records, strings.Join(current, "\n"))
+	}
+
+	return p.parseRecords(ctx, records)
+}
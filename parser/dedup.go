@@ -0,0 +1,102 @@
+package parser
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+)
+
+// DuplicatePolicy defines how the rows with the same main keyword value are merged
+type DuplicatePolicy string
+
+const (
+	DUPLICATE_NEWEST DuplicatePolicy = "newest" // The last row is kept, it comes from the newest message
+	DUPLICATE_SUM    DuplicatePolicy = "sum"    // The values are summed up
+	DUPLICATE_MAX    DuplicatePolicy = "max"    // The largest value of every column is kept
+	DUPLICATE_ERROR  DuplicatePolicy = "error"  // The parse fails
+)
+
+var ErrDuplicateKey = errors.New("duplicate main keyword value")
+
+// mergeDuplicates merges the rows of the same key by the duplicate policy. The rows come from the oldest message first,
+// a merged row keeps the position of the first one. Without the main keyword among the keywords the rows have no key and are kept as is.
+func (p *Parser) mergeDuplicates(ctx context.Context, rows [][]int) ([][]int, error) {
+	keyIndex := p.keyIndex()
+	if keyIndex < 0 {
+		return rows, nil
+	}
+
+	merged := make([][]int, 0, len(rows))
+	positions := make(map[int]int, len(rows))
+	duplicates := make(map[int][][]int)
+
+	for _, row := range rows {
+		key := row[keyIndex]
+
+		position, ok := positions[key]
+		if !ok {
+			positions[key] = len(merged)
+			merged = append(merged, row)
+			continue
+		}
+
+		if duplicates[key] == nil {
+			duplicates[key] = [][]int{merged[position]}
+		}
+		duplicates[key] = append(duplicates[key], row)
+		p.diagnostics.DuplicateRows++
+
+		switch p.duplicatePolicy {
+		case DUPLICATE_ERROR:
+			return nil, fmt.Errorf("%w %d: rows %v", ErrDuplicateKey, key, duplicates[key])
+		case DUPLICATE_NEWEST:
+			merged[position] = row
+		case DUPLICATE_SUM, DUPLICATE_MAX:
+			merged[position] = mergeRows(merged[position], row, keyIndex, p.duplicatePolicy)
+		}
+	}
+
+	keys := make([]int, 0, len(duplicates))
+	for key := range duplicates {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+
+	for _, key := range keys {
+		p.log.WarnContext(ctx, "duplicate rows merged", "key", key, "rows", duplicates[key], "policy", p.duplicatePolicy, "result", merged[positions[key]])
+	}
+
+	return merged, nil
+}
+
+// mergeRows returns a new row, the merged rows may be shared with the parsed data
+func mergeRows(a, b []int, keyIndex int, policy DuplicatePolicy) []int {
+	row := append([]int(nil), a...)
+
+	for i := range row {
+		if i == keyIndex || i >= len(b) {
+			continue
+		}
+
+		switch policy {
+		case DUPLICATE_SUM:
+			row[i] += b[i]
+		case DUPLICATE_MAX:
+			row[i] = max(row[i], b[i])
+		}
+	}
+
+	return row
+}
+
+// keyIndex is the position of the main keyword in the rows, -1 when it is not parsed as a column
+func (p *Parser) keyIndex() int {
+	for i, keyword := range p.keywords {
+		if keyword == p.mainKeyword {
+			return i
+		}
+	}
+
+	return -1
+}
@@ -0,0 +1,66 @@
+package parser
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestMergeDuplicates(t *testing.T) {
+	// The rows of the oldest message come first, the key 12 is repeated by a newer message
+	rows := [][]int{{12, 507, 7}, {14, 301, 0}, {12, 400, 9}, {3, 117, 25}, {12, 600, 1}}
+
+	tests := []struct {
+		policy     DuplicatePolicy
+		want       [][]int
+		wantErr    error
+		duplicates int
+	}{
+		{DUPLICATE_NEWEST, [][]int{{12, 600, 1}, {14, 301, 0}, {3, 117, 25}}, nil, 2},
+		{DUPLICATE_SUM, [][]int{{12, 1507, 17}, {14, 301, 0}, {3, 117, 25}}, nil, 2},
+		{DUPLICATE_MAX, [][]int{{12, 600, 9}, {14, 301, 0}, {3, 117, 25}}, nil, 2},
+		{DUPLICATE_ERROR, nil, ErrDuplicateKey, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.policy), func(t *testing.T) {
+			p := newTestParser(LINE_MODE)
+			p.duplicatePolicy = tt.policy
+
+			input := make([][]int, len(rows))
+			for i, row := range rows {
+				input[i] = append([]int(nil), row...)
+			}
+
+			got, err := p.mergeDuplicates(context.Background(), input)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("mergeDuplicates() error = %v, want %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("mergeDuplicates() = %v, want %v", got, tt.want)
+			}
+			if p.diagnostics.DuplicateRows != tt.duplicates {
+				t.Errorf("duplicate rows = %d, want %d", p.diagnostics.DuplicateRows, tt.duplicates)
+			}
+			if !reflect.DeepEqual(input, rows) {
+				t.Errorf("parsed rows changed to %v", input)
+			}
+		})
+	}
+}
+
+func TestMergeDuplicatesWithoutKey(t *testing.T) {
+	p := newTestParser(LINE_MODE)
+	p.keywords = []string{"ШК", "Коробок"}
+	p.duplicatePolicy = DUPLICATE_ERROR
+
+	rows := [][]int{{1, 2}, {1, 2}}
+	got, err := p.mergeDuplicates(context.Background(), rows)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, rows) {
+		t.Errorf("mergeDuplicates() = %v, want %v", got, rows)
+	}
+}
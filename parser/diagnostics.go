@@ -0,0 +1,185 @@
+package parser
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"slices"
+	"strings"
+)
+
+// LineStatus is what the parser did with a line of the report, or a row of a table
+type LineStatus string
+
+const (
+	LINE_ACCEPTED LineStatus = "accepted" // The line is in the parsed data
+	LINE_HEADER   LineStatus = "header"   // A line without the main keyword before the first record or after the last one
+	LINE_FAILED   LineStatus = "failed"   // A keyword or its number is missing
+	LINE_FILTERED LineStatus = "filtered" // The line was rejected by the key values or the filter
+)
+
+type LineDiagnostic struct {
+	MessageID int64      `json:"message_id"`
+	Text      string     `json:"text"`
+	Status    LineStatus `json:"status"`
+	Keyword   string     `json:"keyword,omitempty"` // The keyword which failed the extraction
+	Rule      string     `json:"rule,omitempty"`    // The rule which filtered the line out
+	Error     string     `json:"error,omitempty"`
+}
+
+// Diagnostics describes what happened to every line of the parsed messages
+type Diagnostics struct {
+	Messages       int               `json:"messages"`
+	TotalLines     int               `json:"total_lines"`
+	AcceptedLines  int               `json:"accepted_lines"`
+	HeaderLines    int               `json:"header_lines"`
+	FailedLines    int               `json:"failed_lines"`
+	FilteredLines  int               `json:"filtered_lines"`
+	DuplicateRows  int               `json:"duplicate_rows"`
+	MissingKeyword map[string]int    `json:"missing_keyword"` // Failed lines by the keyword
+	Lines          []*LineDiagnostic `json:"lines"`
+
+	Drift        *Drift `json:"drift,omitempty"` // The format change of the reply, nil when the format is known
+	DriftChanged bool   `json:"drift_changed"`   // The reply started or stopped drifting from the learned format
+
+	messageID int64
+}
+
+func newDiagnostics() *Diagnostics {
+	return &Diagnostics{MissingKeyword: map[string]int{}}
+}
+
+// startMessage makes the following lines belong to the message
+func (d *Diagnostics) startMessage(messageID int64) {
+	d.Messages++
+	d.messageID = messageID
+}
+
+func (d *Diagnostics) add(text string, status LineStatus) *LineDiagnostic {
+	line := &LineDiagnostic{MessageID: d.messageID, Text: text, Status: status}
+	d.Lines = append(d.Lines, line)
+
+	return line
+}
+
+func (d *Diagnostics) accepted(text string) {
+	d.add(text, LINE_ACCEPTED)
+}
+
+func (d *Diagnostics) header(text string) {
+	d.add(text, LINE_HEADER)
+}
+
+func (d *Diagnostics) failed(text, keyword string, err error) {
+	line := d.add(text, LINE_FAILED)
+	line.Keyword = keyword
+	line.Error = err.Error()
+}
+
+func (d *Diagnostics) filtered(text, rule string) {
+	d.add(text, LINE_FILTERED).Rule = rule
+}
+
+// markEdges turns the lines from the index missing the main keyword before the first record
+// and, when the text was read to the end, after the last record into the header and footer lines
+func (d *Diagnostics) markEdges(from int, mainKeyword string, isComplete bool) {
+	lines := d.Lines[from:]
+
+	isEdge := func(line *LineDiagnostic) bool {
+		return line.Status == LINE_FAILED && line.Keyword == mainKeyword
+	}
+
+	for _, line := range lines {
+		if !isEdge(line) {
+			break
+		}
+		line.Status, line.Keyword, line.Error = LINE_HEADER, "", ""
+	}
+
+	if !isComplete {
+		return
+	}
+
+	for i := len(lines) - 1; i >= 0 && isEdge(lines[i]); i-- {
+		lines[i].Status, lines[i].Keyword, lines[i].Error = LINE_HEADER, "", ""
+	}
+}
+
+// count fills the totals from the lines
+func (d *Diagnostics) count() {
+	d.TotalLines, d.AcceptedLines, d.HeaderLines, d.FailedLines, d.FilteredLines = len(d.Lines), 0, 0, 0, 0
+	clear(d.MissingKeyword)
+
+	for _, line := range d.Lines {
+		switch line.Status {
+		case LINE_ACCEPTED:
+			d.AcceptedLines++
+		case LINE_HEADER:
+			d.HeaderLines++
+		case LINE_FAILED:
+			d.FailedLines++
+			d.MissingKeyword[line.Keyword]++
+		case LINE_FILTERED:
+			d.FilteredLines++
+		}
+	}
+}
+
+// LogValue is the summary logged per tick, the lines themselves are left to the report
+func (d *Diagnostics) LogValue() slog.Value {
+	attrs := []slog.Attr{
+		slog.Int("messages", d.Messages),
+		slog.Int("total", d.TotalLines),
+		slog.Int("accepted", d.AcceptedLines),
+		slog.Int("header", d.HeaderLines),
+		slog.Int("failed", d.FailedLines),
+		slog.Int("filtered", d.FilteredLines),
+		slog.Int("duplicates", d.DuplicateRows),
+	}
+
+	if len(d.MissingKeyword) > 0 {
+		attrs = append(attrs, slog.Any("missing_keyword", d.MissingKeyword))
+	}
+
+	if d.Drift != nil {
+		attrs = append(attrs, slog.Any("format_drift", d.Drift.Reasons))
+	}
+
+	return slog.GroupValue(attrs...)
+}
+
+// WriteReport writes the summary followed by every line with its status
+func (d *Diagnostics) WriteReport(w io.Writer) error {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "messages: %d, lines: %d, accepted: %d, header/footer: %d, failed: %d, filtered: %d, duplicates: %d\n",
+		d.Messages, d.TotalLines, d.AcceptedLines, d.HeaderLines, d.FailedLines, d.FilteredLines, d.DuplicateRows)
+
+	keywords := make([]string, 0, len(d.MissingKeyword))
+	for keyword := range d.MissingKeyword {
+		keywords = append(keywords, keyword)
+	}
+	slices.Sort(keywords)
+
+	for _, keyword := range keywords {
+		fmt.Fprintf(&b, "missing %q: %d\n", keyword, d.MissingKeyword[keyword])
+	}
+
+	if d.Drift != nil {
+		fmt.Fprintf(&b, "format changed: %s\n", strings.Join(d.Drift.Reasons, "; "))
+	}
+
+	for _, line := range d.Lines {
+		fmt.Fprintf(&b, "\n[%d] %-8s %s", line.MessageID, line.Status, strings.ReplaceAll(line.Text, "\n", " / "))
+		switch line.Status {
+		case LINE_FAILED:
+			fmt.Fprintf(&b, "\n           keyword %q: %s", line.Keyword, line.Error)
+		case LINE_FILTERED:
+			fmt.Fprintf(&b, "\n           rule: %s", line.Rule)
+		}
+	}
+	b.WriteString("\n")
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
@@ -0,0 +1,302 @@
+package parser
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"wb-assistance-logistic/metrics"
+	"wb-assistance-logistic/telegramClient"
+)
+
+// Limit of a document and of a file inside an XLSX archive
+const maxDocumentSize = 50 << 20
+
+// A number with a single comma grouping its thousands, e.g. "1,234"
+var thousandsGroup = regexp.MustCompile(`^[-+]?[0-9]{1,3},[0-9]{3}$`)
+
+var (
+	ErrUnsupportedDocument = errors.New("unsupported document format")
+	ErrMissingColumn       = errors.New("column not found in the document header")
+	ErrDocumentTooLarge    = errors.New("document is too large")
+)
+
+// isSupportedDocument reports whether the document is a table the parser can read
+func isSupportedDocument(document *telegramClient.Document) bool {
+	switch strings.ToLower(filepath.Ext(document.FileName)) {
+	case ".xlsx", ".csv":
+		return true
+	}
+
+	return false
+}
+
+func (p *Parser) parseDocument(ctx context.Context, document *telegramClient.Document) ([][]int, error) {
+	p.log.DebugContext(ctx, "downloading document", "file_name", document.FileName, "size", document.Size)
+
+	path, err := p.client.DownloadDocument(document)
+	if err != nil {
+		return nil, err
+	}
+
+	table, err := readTable(path, document.FileName)
+	if err != nil {
+		return nil, fmt.Errorf("read document %q: %w", document.FileName, err)
+	}
+
+	return p.parseTable(ctx, table)
+}
+
+// readTable reads an XLSX or CSV file by the extension of its original name
+func readTable(path, fileName string) ([][]string, error) {
+	switch strings.ToLower(filepath.Ext(fileName)) {
+	case ".xlsx":
+		return readXLSX(path)
+	case ".csv":
+		return readCSV(path)
+	}
+
+	return nil, fmt.Errorf("%w: %s", ErrUnsupportedDocument, fileName)
+}
+
+// readCSV reads a CSV file separated by commas, semicolons or tabs, whichever the header line has most of
+func readCSV(path string) ([][]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("open csv: %w", err)
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(io.LimitReader(file, maxDocumentSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("read csv: %w", err)
+	}
+	if len(data) > maxDocumentSize {
+		return nil, fmt.Errorf("%w: csv is over %d bytes", ErrDocumentTooLarge, maxDocumentSize)
+	}
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+
+	header, _, _ := bytes.Cut(data, []byte("\n"))
+	delimiter := ','
+	for _, candidate := range []rune{';', '\t'} {
+		if bytes.Count(header, []byte(string(candidate))) > bytes.Count(header, []byte(string(delimiter))) {
+			delimiter = candidate
+		}
+	}
+
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.Comma = delimiter
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+
+	table, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("parse csv: %w", err)
+	}
+
+	return table, nil
+}
+
+// parseTable maps the columns to the keywords by the header, the first row with the main keyword column,
+// and applies the same key values filter and skip limit as the text messages
+func (p *Parser) parseTable(ctx context.Context, table [][]string) ([][]int, error) {
+	headerRow, err := p.headerRow(table)
+	if err != nil || headerRow < 0 {
+		return nil, err
+	}
+
+	header := table[headerRow]
+	p.diagnostics.header(joinRow(header))
+
+	mainIndex, err := p.columnIndex(header, p.mainKeyword)
+	if err != nil {
+		return nil, err
+	}
+
+	indexes := make([]int, len(p.keywords))
+	for i, keyword := range p.keywords {
+		indexes[i] = -1
+		if keyword == "" {
+			continue
+		}
+
+		indexes[i], err = p.columnIndex(header, keyword)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var routesData [][]int
+	skipLines := 0
+	from := len(p.diagnostics.Lines)
+
+	for _, row := range table[headerRow+1:] {
+		if skipLines > p.skipLines {
+			p.diagnostics.markEdges(from, p.mainKeyword, false)
+			return nil, fmt.Errorf("%w: %d", ErrSkipLinesExceeded, skipLines)
+		}
+
+		if isEmptyRow(row) {
+			continue
+		}
+
+		number, err := parseCell(row, mainIndex)
+		if err != nil {
+			skipLines++
+			metrics.SkippedLines.Inc()
+			p.diagnostics.failed(joinRow(row), p.mainKeyword, err)
+			p.log.Log(ctx, p.lineLogLevel(), "failed to extract main keyword number", "row", row, "error", err)
+			continue
+		}
+
+		if !p.isContainsValue(number) {
+			p.reject(ctx, joinRow(row), p.keyValuesRule())
+			continue
+		}
+
+		var keyword string
+		numbers := make([]int, len(p.keywords))
+		for i, index := range indexes {
+			if index < 0 {
+				continue
+			}
+
+			numbers[i], err = parseCell(row, index)
+			if err != nil {
+				keyword = p.keywords[i]
+				break
+			}
+		}
+
+		if err != nil {
+			skipLines++
+			metrics.SkippedLines.Inc()
+			p.diagnostics.failed(joinRow(row), keyword, err)
+			p.log.Log(ctx, p.lineLogLevel(), "failed to extract numbers", "row", row, "error", err)
+			continue
+		}
+
+		if !p.isAccepted(ctx, joinRow(row), numbers) {
+			continue
+		}
+
+		p.diagnostics.accepted(joinRow(row))
+		routesData = append(routesData, numbers)
+	}
+
+	p.diagnostics.markEdges(from, p.mainKeyword, true)
+
+	return routesData, nil
+}
+
+// headerRow finds the first row with the main keyword column, so a title line above the header is skipped.
+// It returns -1 for a table without filled rows and ErrMissingColumn when no row has the column.
+func (p *Parser) headerRow(table [][]string) (int, error) {
+	first := -1
+	for i, row := range table {
+		if isEmptyRow(row) {
+			continue
+		}
+		if first < 0 {
+			first = i
+		}
+
+		if _, err := p.columnIndex(row, p.mainKeyword); err == nil {
+			return i, nil
+		}
+	}
+
+	if first < 0 {
+		return -1, nil
+	}
+
+	p.diagnostics.header(joinRow(table[first]))
+	_, err := p.columnIndex(table[first], p.mainKeyword)
+
+	return -1, err
+}
+
+// columnIndex finds the header of the keyword, renamed by the document columns config, ignoring the case
+func (p *Parser) columnIndex(header []string, keyword string) (int, error) {
+	name := keyword
+	if column, ok := p.documentColumns[keyword]; ok {
+		name = column
+	}
+
+	for i, cell := range header {
+		if strings.EqualFold(strings.TrimSpace(cell), name) {
+			return i, nil
+		}
+	}
+
+	return -1, fmt.Errorf("%w: %q", ErrMissingColumn, name)
+}
+
+// parseCell converts a numeric cell, e.g. "12", "12.0" from XLSX, "1 234,5" or "1,234" with a thousands separator
+func parseCell(row []string, index int) (int, error) {
+	if index >= len(row) {
+		return 0, fmt.Errorf("column %d is missing", index+1)
+	}
+
+	number, err := strconv.ParseFloat(normalizeNumber(row[index]), 64)
+	if err != nil {
+		return 0, fmt.Errorf("convert %q to number: %w", row[index], err)
+	}
+
+	return int(math.Round(number)), nil
+}
+
+// normalizeNumber drops the thousands separators and makes the decimal point a dot.
+// Spaces always group thousands. With both a comma and a dot the last one is the decimal point,
+// a repeated separator or a single comma before three last digits groups thousands, otherwise it is the decimal point.
+func normalizeNumber(value string) string {
+	isGrouped := strings.ContainsAny(value, " \u00a0\u202f")
+	value = strings.Map(func(r rune) rune {
+		switch r {
+		case ' ', '\u00a0', '\u202f':
+			return -1
+		}
+		return r
+	}, value)
+
+	commas := strings.Count(value, ",")
+	dots := strings.Count(value, ".")
+
+	switch {
+	case commas > 0 && dots > 0:
+		if strings.LastIndex(value, ",") > strings.LastIndex(value, ".") {
+			return strings.Replace(strings.ReplaceAll(value, ".", ""), ",", ".", 1)
+		}
+		return strings.ReplaceAll(value, ",", "")
+	case commas > 1:
+		return strings.ReplaceAll(value, ",", "")
+	case dots > 1:
+		return strings.ReplaceAll(value, ".", "")
+	case commas == 1 && !isGrouped && thousandsGroup.MatchString(value):
+		return strings.Replace(value, ",", "", 1)
+	}
+
+	return strings.Replace(value, ",", ".", 1)
+}
+
+func joinRow(row []string) string {
+	return strings.Join(row, " | ")
+}
+
+func isEmptyRow(row []string) bool {
+	for _, cell := range row {
+		if strings.TrimSpace(cell) != "" {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,140 @@
+package parser
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestParseCell(t *testing.T) {
+	tests := []struct {
+		cell    string
+		want    int
+		wantErr bool
+	}{
+		{"12", 12, false},
+		{"12.0", 12, false},
+		{"12.6", 13, false},
+		{"1,5", 2, false},
+		{"1,234", 1234, false},
+		{"-1,234", -1234, false},
+		{"12,34", 12, false},
+		{"1,2345", 1, false},
+		{"1 234", 1234, false},
+		{"1\u00a0234", 1234, false},
+		{"1\u202f234\u202f567", 1234567, false},
+		{"1 234,5", 1235, false},
+		{"1 234,567", 1235, false},
+		{"1,234,567", 1234567, false},
+		{"1.234.567", 1234567, false},
+		{"1,234.5", 1235, false},
+		{"1.234,5", 1235, false},
+		{"", 0, true},
+		{"abc", 0, true},
+	}
+
+	for _, tt := range tests {
+		got, err := parseCell([]string{tt.cell}, 0)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseCell(%q) error = %v, want error %v", tt.cell, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("parseCell(%q) = %d, want %d", tt.cell, got, tt.want)
+		}
+	}
+
+	if _, err := parseCell([]string{"1"}, 1); err == nil {
+		t.Error("parseCell of a missing column returned no error")
+	}
+}
+
+func TestParseTableHeader(t *testing.T) {
+	tests := []struct {
+		name    string
+		table   [][]string
+		want    [][]int
+		wantErr error
+	}{
+		{
+			name:  "header in the first row",
+			table: [][]string{{"Парковка", "Склад назначения", "Коробок"}, {"12", "507", "7"}},
+			want:  [][]int{{12, 507, 7}},
+		},
+		{
+			name:  "title lines above the header",
+			table: [][]string{{"Отчет по маршрутам"}, {}, {"Склад", "Дата"}, {"Парковка", "Склад назначения", "Коробок"}, {"12", "507", "7"}},
+			want:  [][]int{{12, 507, 7}},
+		},
+		{
+			name:    "no main keyword column",
+			table:   [][]string{{"Отчет по маршрутам"}, {"12", "507", "7"}},
+			wantErr: ErrMissingColumn,
+		},
+		{
+			name:  "empty table",
+			table: [][]string{{""}, {" "}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := newTestParser(TABLE_MODE).parseTable(context.Background(), tt.table)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("parseTable() error = %v, want %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseTable() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReadCSVTooLarge(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "routes.csv")
+	if err := os.WriteFile(path, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Truncate(path, maxDocumentSize+1); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := readCSV(path); !errors.Is(err, ErrDocumentTooLarge) {
+		t.Errorf("readCSV() error = %v, want %v", err, ErrDocumentTooLarge)
+	}
+}
+
+func TestXLSXColumnIndex(t *testing.T) {
+	tests := []struct {
+		ref     string
+		want    int
+		wantErr bool
+	}{
+		{"A1", 0, false},
+		{"Z9", 25, false},
+		{"AB12", 27, false},
+		{"XFD1048576", 16383, false},
+		{"XFE1", 0, true},
+		{"AAAAAAAAAAAAAAAAAAAAAAAAAAAAAA1", 0, true},
+		{"A0", 0, true},
+		{"A1048577", 0, true},
+		{"A", 0, true},
+		{"A+1", 0, true},
+		{"1", 0, true},
+		{"", 0, true},
+	}
+
+	for _, tt := range tests {
+		got, err := xlsxColumnIndex(tt.ref)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("xlsxColumnIndex(%q) error = %v, want error %v", tt.ref, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("xlsxColumnIndex(%q) = %d, want %d", tt.ref, got, tt.want)
+		}
+	}
+}
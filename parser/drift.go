@@ -0,0 +1,289 @@
+package parser
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"wb-assistance-logistic/config"
+)
+
+const (
+	DEFAULT_DRIFT_LEARN_TICKS = 5
+	DEFAULT_DRIFT_THRESHOLD   = 0.5
+
+	// Lines and characters of the reply sent with the alert
+	DRIFT_SAMPLE_LINES = 10
+	DRIFT_SAMPLE_SIZE  = 1000
+
+	// A reply this many times shorter or longer than the learned ones is reported along with the drift
+	driftLinesFactor = 3
+)
+
+var (
+	digitsPattern = regexp.MustCompile(`[0-9]+`)
+	spacesPattern = regexp.MustCompile(`\s+`)
+)
+
+// Fingerprint is the structure of a reply without its numbers
+type Fingerprint struct {
+	Shapes   map[string]int  // Lines with the numbers replaced by "#" and their count
+	Keywords map[string]bool // Keywords found in the reply
+	Lines    int             // Non-blank lines
+}
+
+// Baseline is the format learned from the replies without a drift
+type Baseline struct {
+	Shapes   map[string]bool `json:"shapes"`
+	Keywords map[string]bool `json:"keywords"` // Keywords found in every learned reply
+	MinLines int             `json:"min_lines"`
+	MaxLines int             `json:"max_lines"`
+	Learned  int             `json:"learned"`
+}
+
+// Drift is a significant change of the reply format against the baseline
+type Drift struct {
+	Reasons []string `json:"reasons"`
+	Sample  string   `json:"sample"`
+}
+
+// Message returns the text of the format changed alert
+func (d *Drift) Message() string {
+	return fmt.Sprintf("format changed: %s\n\n%s", strings.Join(d.Reasons, "; "), d.Sample)
+}
+
+// driftDetector compares every reply with the baseline learned from the first replies.
+// The baseline is frozen after learnTicks replies, so a format changing bit by bit is still reported.
+// A drift is significant when a keyword found in every learned reply is missing
+// or when the share of the lines of an unknown shape exceeds the threshold.
+type driftDetector struct {
+	keywords   []string
+	learnTicks int
+	threshold  float64
+	path       string
+	baseline   *Baseline
+	isDrifted  bool
+	isReadOnly bool // The baseline is learned in memory only
+	log        *slog.Logger
+}
+
+func newDriftDetector(cfg *config.FormatDrift, keywords []string, log *slog.Logger) (*driftDetector, error) {
+	if cfg.LearnTicks < 0 || cfg.Threshold < 0 || cfg.Threshold > 1 {
+		return nil, fmt.Errorf("invalid format drift %+v", *cfg)
+	}
+
+	d := &driftDetector{
+		keywords:   keywords,
+		learnTicks: cfg.LearnTicks,
+		threshold:  cfg.Threshold,
+		path:       cfg.BaselineFile,
+		log:        log,
+	}
+
+	if d.learnTicks == 0 {
+		d.learnTicks = DEFAULT_DRIFT_LEARN_TICKS
+	}
+	if d.threshold == 0 {
+		d.threshold = DEFAULT_DRIFT_THRESHOLD
+	}
+
+	if d.path == "" {
+		return d, nil
+	}
+
+	file, err := os.ReadFile(d.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return d, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read format baseline file: %w", err)
+	}
+
+	err = json.Unmarshal(file, &d.baseline)
+	if err != nil {
+		return nil, fmt.Errorf("unmarshal format baseline file: %w", err)
+	}
+
+	if d.baseline != nil && d.baseline.Shapes == nil {
+		d.baseline.Shapes = map[string]bool{}
+	}
+
+	return d, nil
+}
+
+// check compares the reply with the baseline once it is learned.
+// It returns the drift of the reply, nil when the format is known or still being learned,
+// and whether the drift state changed since the previous reply.
+func (d *driftDetector) check(text string) (*Drift, bool) {
+	var drift *Drift
+	if d.isLearned() {
+		drift = d.compare(d.fingerprint(text), text)
+	}
+
+	changed := d.isDrifted != (drift != nil)
+	d.isDrifted = drift != nil
+
+	return drift, changed
+}
+
+func (d *driftDetector) isLearned() bool {
+	return d.baseline != nil && d.baseline.Learned >= d.learnTicks
+}
+
+func (d *driftDetector) fingerprint(text string) *Fingerprint {
+	fingerprint := &Fingerprint{Shapes: map[string]int{}, Keywords: map[string]bool{}}
+
+	for _, line := range strings.Split(text, "\n") {
+		shape := lineShape(line)
+		if shape == "" {
+			continue
+		}
+
+		fingerprint.Lines++
+		fingerprint.Shapes[shape]++
+	}
+
+	for _, keyword := range d.keywords {
+		if keyword != "" && strings.Contains(text, keyword) {
+			fingerprint.Keywords[keyword] = true
+		}
+	}
+
+	return fingerprint
+}
+
+func (d *driftDetector) compare(fingerprint *Fingerprint, text string) *Drift {
+	var reasons []string
+	isSignificant := false
+
+	for _, keyword := range d.keywords {
+		if d.baseline.Keywords[keyword] && !fingerprint.Keywords[keyword] {
+			reasons = append(reasons, fmt.Sprintf("keyword %q is missing", keyword))
+			isSignificant = true
+		}
+	}
+
+	unknown := 0
+	for shape, count := range fingerprint.Shapes {
+		if !d.baseline.Shapes[shape] {
+			unknown += count
+		}
+	}
+
+	if fingerprint.Lines > 0 {
+		share := float64(unknown) / float64(fingerprint.Lines)
+		if share > d.threshold {
+			reasons = append(reasons, fmt.Sprintf("%d of %d lines have an unknown layout", unknown, fingerprint.Lines))
+			isSignificant = true
+		}
+	}
+
+	if fingerprint.Lines*driftLinesFactor < d.baseline.MinLines || fingerprint.Lines > d.baseline.MaxLines*driftLinesFactor {
+		reasons = append(reasons, fmt.Sprintf("%d lines instead of %d-%d", fingerprint.Lines, d.baseline.MinLines, d.baseline.MaxLines))
+	}
+
+	if !isSignificant {
+		return nil
+	}
+
+	return &Drift{Reasons: reasons, Sample: sample(text)}
+}
+
+// learn adds the reply to the baseline while it is not complete. It is called only for the replies
+// which gave rows, so an error reply like "service unavailable" never becomes the known format.
+func (d *driftDetector) learn(text string) {
+	if d.isLearned() {
+		return
+	}
+
+	fingerprint := d.fingerprint(text)
+	if d.baseline == nil {
+		d.baseline = &Baseline{
+			Shapes:   map[string]bool{},
+			Keywords: fingerprint.Keywords,
+			MinLines: fingerprint.Lines,
+			MaxLines: fingerprint.Lines,
+		}
+	}
+
+	for shape := range fingerprint.Shapes {
+		d.baseline.Shapes[shape] = true
+	}
+
+	for keyword := range d.baseline.Keywords {
+		if !fingerprint.Keywords[keyword] {
+			delete(d.baseline.Keywords, keyword)
+		}
+	}
+
+	d.baseline.MinLines = min(d.baseline.MinLines, fingerprint.Lines)
+	d.baseline.MaxLines = max(d.baseline.MaxLines, fingerprint.Lines)
+	d.baseline.Learned++
+
+	err := d.save()
+	if err != nil {
+		d.log.Warn("failed to save format baseline", "error", err)
+	}
+}
+
+// save writes the baseline to a temporary file and renames it, so a crash never leaves a truncated file
+func (d *driftDetector) save() error {
+	if d.path == "" || d.isReadOnly {
+		return nil
+	}
+
+	data, err := json.Marshal(d.baseline)
+	if err != nil {
+		return fmt.Errorf("marshal format baseline: %w", err)
+	}
+
+	err = os.MkdirAll(filepath.Dir(d.path), 0o755)
+	if err != nil {
+		return fmt.Errorf("create format baseline directory: %w", err)
+	}
+
+	tmp := d.path + ".tmp"
+	err = os.WriteFile(tmp, data, 0o644)
+	if err != nil {
+		return fmt.Errorf("write format baseline file: %w", err)
+	}
+
+	err = os.Rename(tmp, d.path)
+	if err != nil {
+		return fmt.Errorf("rename format baseline file: %w", err)
+	}
+
+	return nil
+}
+
+// lineShape replaces the numbers of the line with "#" and collapses the spaces, a blank line has no shape
+func lineShape(line string) string {
+	shape := digitsPattern.ReplaceAllString(line, "#")
+	return strings.TrimSpace(spacesPattern.ReplaceAllString(shape, " "))
+}
+
+// sample returns the first lines of the reply for the alert
+func sample(text string) string {
+	var lines []string
+	for _, line := range strings.Split(text, "\n") {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+
+		lines = append(lines, line)
+		if len(lines) == DRIFT_SAMPLE_LINES {
+			break
+		}
+	}
+
+	result := strings.Join(lines, "\n")
+	if runes := []rune(result); len(runes) > DRIFT_SAMPLE_SIZE {
+		result = string(runes[:DRIFT_SAMPLE_SIZE]) + "…"
+	}
+
+	return result
+}
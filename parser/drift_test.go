@@ -0,0 +1,145 @@
+package parser
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"wb-assistance-logistic/config"
+)
+
+func routesReply(rows ...[2]int) string {
+	lines := []string{"Маршруты склада"}
+	for _, row := range rows {
+		lines = append(lines, fmt.Sprintf("Парковка %d, Коробок %d", row[0], row[1]))
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+func newTestDriftDetector(t *testing.T, cfg *config.FormatDrift) *driftDetector {
+	t.Helper()
+
+	d, err := newDriftDetector(cfg, []string{"Парковка", "Коробок"}, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return d
+}
+
+func TestDriftAfterBaseline(t *testing.T) {
+	d := newTestDriftDetector(t, &config.FormatDrift{LearnTicks: 3})
+
+	for i := 0; i < 3; i++ {
+		text := routesReply([2]int{i, 10 * i}, [2]int{i + 1, 5})
+		if drift, _ := d.check(text); drift != nil {
+			t.Fatalf("drift while learning: %v", drift.Reasons)
+		}
+		d.learn(text)
+	}
+
+	steps := []struct {
+		name        string
+		text        string
+		wantDrift   bool
+		wantChanged bool
+	}{
+		{"known format with other numbers", routesReply([2]int{40, 1}, [2]int{41, 2}, [2]int{42, 3}), false, false},
+		{"renamed keyword", "Маршруты склада\nМесто 40, Коробок 1\nМесто 41, Коробок 2", true, true},
+		{"still changed", "Маршруты склада\nМесто 40, Коробок 1", true, false},
+		{"back to the known format", routesReply([2]int{40, 1}), false, true},
+		{"new layout of most lines", "Маршруты склада\nПарковка 40 / Коробок 1\nПарковка 41 / Коробок 2\nПарковка 42 / Коробок 3", true, true},
+	}
+
+	for _, step := range steps {
+		drift, changed := d.check(step.text)
+		if (drift != nil) != step.wantDrift || changed != step.wantChanged {
+			t.Errorf("%s: drift = %v, changed = %v, want drift %v, changed %v", step.name, drift, changed, step.wantDrift, step.wantChanged)
+		}
+	}
+}
+
+// A format changing one line at a time is reported once the changed lines exceed the threshold
+func TestDriftBaselineFrozen(t *testing.T) {
+	d := newTestDriftDetector(t, &config.FormatDrift{LearnTicks: 1, Threshold: 0.5})
+
+	d.learn(routesReply([2]int{1, 1}, [2]int{2, 2}, [2]int{3, 3}))
+
+	steps := []string{
+		"Маршруты склада\nПарковка 1, Коробок 1\nПарковка 2, Коробок 2\nПарковка 3 / Коробок 3",
+		"Маршруты склада\nПарковка 1, Коробок 1\nПарковка 2 / Коробок 2\nПарковка 3 / Коробок 3",
+		"Маршруты склада\nПарковка 1 / Коробок 1\nПарковка 2 / Коробок 2\nПарковка 3 / Коробок 3",
+	}
+
+	var drift *Drift
+	for _, text := range steps {
+		drift, _ = d.check(text)
+		d.learn(text)
+	}
+
+	if drift == nil {
+		t.Fatal("no drift after the format changed")
+	}
+	if d.baseline.Learned != 1 {
+		t.Errorf("learned %d replies, want 1", d.baseline.Learned)
+	}
+	if !strings.Contains(drift.Sample, "Парковка 1 / Коробок 1") {
+		t.Errorf("sample %q is not the changed reply", drift.Sample)
+	}
+}
+
+func TestDriftBaselineFile(t *testing.T) {
+	cfg := &config.FormatDrift{LearnTicks: 2, BaselineFile: filepath.Join(t.TempDir(), "baseline.json")}
+
+	d := newTestDriftDetector(t, cfg)
+	d.learn(routesReply([2]int{1, 1}))
+	d.learn(routesReply([2]int{2, 2}))
+
+	// The restarted detector compares with the saved baseline right away
+	restarted := newTestDriftDetector(t, cfg)
+	drift, changed := restarted.check("Маршруты склада\nМесто 1, Коробок 1")
+	if drift == nil || !changed {
+		t.Errorf("drift = %v, changed = %v, want a new drift", drift, changed)
+	}
+}
+
+// The replies without rows, e.g. an error of the bot, are not learned as the report format
+func TestLearnFormatWithRowsOnly(t *testing.T) {
+	p := newTestParser(LINE_MODE)
+	p.drift = newTestDriftDetector(t, &config.FormatDrift{LearnTicks: 1})
+
+	p.learnFormat("Сервис временно недоступен", nil)
+	if p.drift.isLearned() {
+		t.Fatal("baseline learned from a reply without rows")
+	}
+
+	p.learnFormat(routesReply([2]int{1, 1}), [][]int{{1, 1}})
+	if !p.drift.isLearned() {
+		t.Fatal("baseline not learned from a reply with rows")
+	}
+
+	drift, _ := p.drift.check("Сервис временно недоступен")
+	if drift == nil {
+		t.Error("no drift for the error reply against the learned report")
+	}
+}
+
+func TestDriftBaselineReadOnly(t *testing.T) {
+	cfg := &config.FormatDrift{LearnTicks: 1, BaselineFile: filepath.Join(t.TempDir(), "baseline.json")}
+
+	p := newTestParser(LINE_MODE)
+	p.drift = newTestDriftDetector(t, cfg)
+	p.SetReadOnly(true)
+
+	p.learnFormat(routesReply([2]int{1, 1}), [][]int{{1, 1}})
+
+	if _, err := os.Stat(cfg.BaselineFile); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("baseline file stat error = %v, want the file not to exist", err)
+	}
+}
@@ -0,0 +1,226 @@
+package parser
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"wb-assistance-logistic/config"
+	"wb-assistance-logistic/utils"
+)
+
+// rowFilter is a compiled condition on a parsed row, the row is ordered as the keywords
+type rowFilter interface {
+	// reject returns the condition rejecting the row, nil when the row passes
+	reject(row []int) rowFilter
+	String() string
+}
+
+type compareFilter struct {
+	field string
+	index int
+	op    string
+	value int
+}
+
+type inFilter struct {
+	field  string
+	index  int
+	values []int
+	negate bool
+}
+
+type rangeFilter struct {
+	field    string
+	index    int
+	from, to *int
+}
+
+type allFilter []rowFilter
+
+type anyFilter []rowFilter
+
+type notFilter struct {
+	filter rowFilter
+}
+
+// newRowFilter compiles the filter config, the fields are resolved to the positions of the keywords
+func newRowFilter(cfg *config.RowFilter, keywords []string, mainKeyword string) (rowFilter, error) {
+	if cfg == nil {
+		return nil, errors.New("filter can not be nil")
+	}
+
+	conditions := 0
+	for _, isSet := range []bool{
+		cfg.Op != "" || cfg.Value != nil,
+		cfg.In != nil,
+		cfg.NotIn != nil,
+		cfg.From != nil || cfg.To != nil,
+		cfg.All != nil,
+		cfg.Any != nil,
+		cfg.Not != nil,
+	} {
+		if isSet {
+			conditions++
+		}
+	}
+
+	if conditions != 1 {
+		return nil, fmt.Errorf("filter must set exactly one condition, got %d", conditions)
+	}
+
+	switch {
+	case cfg.All != nil || cfg.Any != nil:
+		children := cfg.All
+		if cfg.Any != nil {
+			children = cfg.Any
+		}
+
+		filters := make([]rowFilter, 0, len(children))
+		for _, child := range children {
+			filter, err := newRowFilter(child, keywords, mainKeyword)
+			if err != nil {
+				return nil, err
+			}
+			filters = append(filters, filter)
+		}
+
+		if cfg.Any != nil {
+			return anyFilter(filters), nil
+		}
+		return allFilter(filters), nil
+
+	case cfg.Not != nil:
+		filter, err := newRowFilter(cfg.Not, keywords, mainKeyword)
+		if err != nil {
+			return nil, err
+		}
+		return &notFilter{filter: filter}, nil
+	}
+
+	field := cfg.Field
+	if field == "" {
+		field = mainKeyword
+	}
+
+	index := slices.Index(keywords, field)
+	if index < 0 {
+		return nil, fmt.Errorf("filter field %q is not a keyword", field)
+	}
+
+	switch {
+	case cfg.In != nil:
+		return &inFilter{field: field, index: index, values: cfg.In}, nil
+	case cfg.NotIn != nil:
+		return &inFilter{field: field, index: index, values: cfg.NotIn, negate: true}, nil
+	case cfg.From != nil || cfg.To != nil:
+		if cfg.From != nil && cfg.To != nil && *cfg.From > *cfg.To {
+			return nil, fmt.Errorf("filter range of %q from %d is greater than to %d", field, *cfg.From, *cfg.To)
+		}
+		return &rangeFilter{field: field, index: index, from: cfg.From, to: cfg.To}, nil
+	}
+
+	if cfg.Value == nil {
+		return nil, fmt.Errorf("filter comparison of %q has no value", field)
+	}
+
+	if _, err := utils.Compare(0, cfg.Op, 0); err != nil {
+		return nil, err
+	}
+
+	return &compareFilter{field: field, index: index, op: cfg.Op, value: *cfg.Value}, nil
+}
+
+func (f *compareFilter) reject(row []int) rowFilter {
+	if ok, _ := utils.Compare(row[f.index], f.op, f.value); ok {
+		return nil
+	}
+	return f
+}
+
+func (f *compareFilter) String() string {
+	return fmt.Sprintf("%s %s %d", f.field, f.op, f.value)
+}
+
+func (f *inFilter) reject(row []int) rowFilter {
+	if slices.Contains(f.values, row[f.index]) != f.negate {
+		return nil
+	}
+	return f
+}
+
+func (f *inFilter) String() string {
+	if f.negate {
+		return fmt.Sprintf("%s not in %v", f.field, f.values)
+	}
+	return fmt.Sprintf("%s in %v", f.field, f.values)
+}
+
+func (f *rangeFilter) reject(row []int) rowFilter {
+	value := row[f.index]
+	if (f.from != nil && value < *f.from) || (f.to != nil && value > *f.to) {
+		return f
+	}
+	return nil
+}
+
+func (f *rangeFilter) String() string {
+	switch {
+	case f.from == nil:
+		return fmt.Sprintf("%s <= %d", f.field, *f.to)
+	case f.to == nil:
+		return fmt.Sprintf("%s >= %d", f.field, *f.from)
+	}
+	return fmt.Sprintf("%s in %d..%d", f.field, *f.from, *f.to)
+}
+
+// reject returns the first condition rejecting the row, so the log points at the exact rule
+func (f allFilter) reject(row []int) rowFilter {
+	for _, filter := range f {
+		if rejected := filter.reject(row); rejected != nil {
+			return rejected
+		}
+	}
+	return nil
+}
+
+func (f allFilter) String() string {
+	return joinFilters("all", f)
+}
+
+func (f anyFilter) reject(row []int) rowFilter {
+	for _, filter := range f {
+		if filter.reject(row) == nil {
+			return nil
+		}
+	}
+
+	if len(f) == 0 {
+		return nil
+	}
+	return f
+}
+
+func (f anyFilter) String() string {
+	return joinFilters("any", f)
+}
+
+func (f *notFilter) reject(row []int) rowFilter {
+	if f.filter.reject(row) == nil {
+		return f
+	}
+	return nil
+}
+
+func (f *notFilter) String() string {
+	return fmt.Sprintf("not(%s)", f.filter)
+}
+
+func joinFilters(name string, filters []rowFilter) string {
+	parts := make([]string, len(filters))
+	for i, filter := range filters {
+		parts[i] = filter.String()
+	}
+
+	return fmt.Sprintf("%s(%s)", name, strings.Join(parts, ", "))
+}
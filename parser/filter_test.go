@@ -0,0 +1,100 @@
+package parser
+
+import (
+	"encoding/json"
+	"testing"
+	"wb-assistance-logistic/config"
+)
+
+var filterKeywords = []string{"Парковка", "ШК", "Коробок"}
+
+func compileFilter(t *testing.T, text string) (rowFilter, error) {
+	t.Helper()
+
+	var cfg config.RowFilter
+	if err := json.Unmarshal([]byte(text), &cfg); err != nil {
+		t.Fatalf("unmarshal filter: %v", err)
+	}
+
+	return newRowFilter(&cfg, filterKeywords, "Парковка")
+}
+
+func TestRowFilterReject(t *testing.T) {
+	const nested = `{"all": [
+		{"from": 1, "to": 20},
+		{"not_in": [13]},
+		{"any": [{"field": "Коробок", "op": ">", "value": 0}, {"field": "ШК", "from": 10, "to": 500}]}
+	]}`
+	const negated = `{"not": {"any": [
+		{"in": [7, 8]},
+		{"all": [{"field": "ШК", "op": ">=", "value": 100}, {"field": "Коробок", "op": "==", "value": 0}]}
+	]}}`
+
+	tests := []struct {
+		name   string
+		filter string
+		row    []int
+		want   string // Rejecting rule, empty when the row passes
+	}{
+		{"range lower bound is inclusive", nested, []int{1, 10, 0}, ""},
+		{"range upper bound is inclusive", nested, []int{20, 500, 0}, ""},
+		{"below the range", nested, []int{0, 50, 1}, "Парковка in 1..20"},
+		{"above the range", nested, []int{21, 50, 1}, "Парковка in 1..20"},
+		{"excluded key", nested, []int{13, 50, 1}, "Парковка not in [13]"},
+		{"any passes by the first condition", nested, []int{5, 0, 3}, ""},
+		{"any passes by the second condition", nested, []int{5, 300, 0}, ""},
+		{"any rejects as a whole", nested, []int{5, 501, 0}, "any(Коробок > 0, ШК in 10..500)"},
+		{"not rejects a listed key", negated, []int{7, 0, 5}, "not(any(Парковка in [7 8], all(ШК >= 100, Коробок == 0)))"},
+		{"not rejects a nested all", negated, []int{9, 100, 0}, "not(any(Парковка in [7 8], all(ШК >= 100, Коробок == 0)))"},
+		{"not passes a failed nested all", negated, []int{9, 100, 1}, ""},
+		{"not passes below the comparison", negated, []int{9, 99, 0}, ""},
+		{"open range from", `{"from": 5}`, []int{4, 0, 0}, "Парковка >= 5"},
+		{"open range from bound", `{"from": 5}`, []int{5, 0, 0}, ""},
+		{"open range to", `{"field": "Коробок", "to": 10}`, []int{1, 0, 11}, "Коробок <= 10"},
+		{"open range to bound", `{"field": "Коробок", "to": 10}`, []int{1, 0, 10}, ""},
+		{"double negation", `{"not": {"not": {"op": "==", "value": 3}}}`, []int{4, 0, 0}, "not(not(Парковка == 3))"},
+		{"double negation passes", `{"not": {"not": {"op": "==", "value": 3}}}`, []int{3, 0, 0}, ""},
+		{"empty any passes", `{"any": []}`, []int{1, 0, 0}, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filter, err := compileFilter(t, tt.filter)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got := ""
+			if rejected := filter.reject(tt.row); rejected != nil {
+				got = rejected.String()
+			}
+
+			if got != tt.want {
+				t.Errorf("reject(%v) = %q, want %q", tt.row, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewRowFilterInvalid(t *testing.T) {
+	tests := []struct {
+		name   string
+		filter string
+	}{
+		{"no condition", `{}`},
+		{"two conditions", `{"in": [1], "from": 1}`},
+		{"unknown field", `{"field": "Паллет", "in": [1]}`},
+		{"unknown operator", `{"op": "=", "value": 1}`},
+		{"comparison without a value", `{"op": ">"}`},
+		{"reversed range", `{"from": 10, "to": 1}`},
+		{"invalid nested condition", `{"all": [{"in": [1]}, {"not": {}}]}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := compileFilter(t, tt.filter); err == nil {
+				t.Error("newRowFilter returned no error")
+			}
+		})
+	}
+}
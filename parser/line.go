@@ -0,0 +1,148 @@
+package parser
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"wb-assistance-logistic/metrics"
+)
+
+// parseLines reads one record per line with every keyword on that line
+func (p *Parser) parseLines(ctx context.Context, text string) ([][]int, error) {
+	return p.parseRecords(ctx, strings.Split(text, "\n"))
+}
+
+// parseRecords extracts the requested records, the records without the values count against the skip limit
+func (p *Parser) parseRecords(ctx context.Context, records []string) ([][]int, error) {
+	var routesData [][]int
+	skipLines := 0
+	from := len(p.diagnostics.Lines)
+
+	for _, record := range records {
+		if skipLines > p.skipLines {
+			p.diagnostics.markEdges(from, p.mainKeyword, false)
+			return nil, fmt.Errorf("%w: %d", ErrSkipLinesExceeded, skipLines)
+		}
+		// Retrieve the number of the main keyword, checking for the presence of the main keyword
+		// If there are more missing lines than can be skipped, we exit the function
+		number, err := extractNumberAfterKeyword(record, p.mainKeyword)
+		if err != nil {
+			skipLines++
+			metrics.SkippedLines.Inc()
+			p.diagnostics.failed(record, p.mainKeyword, err)
+			p.log.Log(ctx, p.lineLogLevel(), "failed to extract main keyword number", "line", record, "error", err)
+			continue
+		}
+
+		// Checking whether a string should be added to the array
+		if !p.isContainsValue(number) {
+			p.reject(ctx, record, p.keyValuesRule())
+			continue
+		}
+
+		numbers, keyword, err := p.extractNumbers(record)
+		if err != nil {
+			skipLines++
+			metrics.SkippedLines.Inc()
+			p.diagnostics.failed(record, keyword, err)
+			p.log.Log(ctx, p.lineLogLevel(), "failed to extract numbers", "line", record, "error", err)
+			continue
+		}
+
+		if !p.isAccepted(ctx, record, numbers) {
+			continue
+		}
+
+		p.diagnostics.accepted(record)
+		routesData = append(routesData, numbers)
+	}
+
+	p.diagnostics.markEdges(from, p.mainKeyword, true)
+
+	return routesData, nil
+}
+
+// isContainsValue checks the main keyword number against the key values, no key values accept every number
+func (p *Parser) isContainsValue(value int) bool {
+	if p.keyValues == nil {
+		return true
+	}
+
+	for i := 0; i < len(p.keyValues); i++ {
+		if p.keyValues[i] == value {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (p *Parser) keyValuesRule() string {
+	return fmt.Sprintf("%s in key_values %v", p.mainKeyword, p.keyValues)
+}
+
+// isAccepted applies the filter to the parsed row, the text is the line or the joined table row
+func (p *Parser) isAccepted(ctx context.Context, text string, numbers []int) bool {
+	if p.filter == nil {
+		return true
+	}
+
+	rejected := p.filter.reject(numbers)
+	if rejected == nil {
+		return true
+	}
+
+	p.reject(ctx, text, rejected.String())
+	return false
+}
+
+func (p *Parser) reject(ctx context.Context, text string, rule string) {
+	p.diagnostics.filtered(text, rule)
+	p.log.DebugContext(ctx, "row rejected", "row", text, "rule", rule)
+}
+
+// extractNumbers returns the numbers of the keywords, or the keyword failing the extraction
+func (p *Parser) extractNumbers(text string) ([]int, string, error) {
+	numbers := make([]int, len(p.keywords))
+
+	var err error
+
+	for i, keyword := range p.keywords {
+		if len(keyword) == 0 {
+			continue
+		}
+
+		numbers[i], err = extractNumberAfterKeyword(text, keyword)
+		if err != nil {
+			return nil, keyword, err
+		}
+	}
+	return numbers, "", nil
+}
+
+func extractNumberAfterKeyword(text, keyword string) (int, error) {
+	start := strings.Index(text, keyword)
+	if start == -1 {
+		return 0, fmt.Errorf("keyword %q not found in text", keyword)
+	}
+
+	start += len(keyword)
+
+	// The value is on the line of the keyword, a block record never takes it from the next line
+	for start < len(text) && text[start] != '\n' && (text[start] < '0' || text[start] > '9') {
+		start++
+	}
+
+	end := start
+	for end < len(text) && text[end] >= '0' && text[end] <= '9' {
+		end++
+	}
+
+	number, err := strconv.Atoi(text[start:end])
+	if err != nil {
+		return 0, fmt.Errorf("convert %q after keyword %q to number: %w", text[start:end], keyword, err)
+	}
+
+	return number, nil
+}
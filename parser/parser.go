@@ -1,265 +1,410 @@
 package parser
 
 import (
-	"strconv"
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
 	"strings"
 	"time"
 	"wb-assistance-logistic/config"
 	"wb-assistance-logistic/logger"
+	"wb-assistance-logistic/metrics"
 	"wb-assistance-logistic/telegramClient"
 )
 
+// ParseMode defines how the records are laid out in the text of a message
+type ParseMode string
+
+const (
+	LINE_MODE  ParseMode = "line"  // A record per line with every keyword on it
+	BLOCK_MODE ParseMode = "block" // A record over several lines starting at the record start marker
+	TABLE_MODE ParseMode = "table" // A monospace table with a header, usually in pre entities
+)
+
+var (
+	ErrNoMessages        = errors.New("no messages")
+	ErrSkipLinesExceeded = errors.New("the permissible skip line value has been exceeded")
+)
+
+type sortKey struct {
+	index int
+	desc  bool
+}
+
 type Parser struct {
 	client *telegramClient.Client
+	log    *slog.Logger
 
 	chatID            int64
 	chatUsername      string
 	countReadMessages int32
 	skipLines         int
-	sortIndex         int
-	isSort            bool
-	isInvertSort      bool
+	sortKeys          []sortKey
+	mode              ParseMode
+	recordStart       string
 
 	warehouseID string
 	mainKeyword string
-	sortKeyword string
 	keywords    []string
 	keyValues   []int
+	filter      rowFilter
 
-	data [][]int
+	duplicatePolicy DuplicatePolicy
+	drift           *driftDetector
+
+	documentColumns map[string]string
+
+	data        [][]int
+	diagnostics *Diagnostics
 
 	commandRequestWarehouseRoutes string
 	requestTimeSleep              time.Duration
+	editQuietPeriod               time.Duration
+	replyTimeout                  time.Duration
 }
 
 func NewParser(cfg *config.Parser, client *telegramClient.Client) (*Parser, error) {
 	if !client.IsAuth() {
-		return nil, logger.Error("Parser.NewParser()", "Telegram client is not auth")
-	}
-
-	validationErrors := map[string]bool{
-		"Invalid chat username: ":                       cfg.ChatUsername == "",
-		"Invalid count read messages: ":                 cfg.CountReadMessages == 0,
-		"Invalid count skip lines: ":                    cfg.SkipLines < 0,
-		"Invalid warehouse id: ":                        len(cfg.WarehouseID) <= 4,
-		"Invalid warehouse main keyword: ":              cfg.MainKeyword == "",
-		"Invalid warehouse command request warehouse: ": cfg.CommandRequestData == "",
-		"Invalid warehouse keywords: ":                  cfg.Keywords == nil,
-		"Invalid warehouse key values: ":                cfg.KeyValues == nil,
-		"Invalid sort keyword: ":                        cfg.IsSort && cfg.SortKeyword == "",
-		"Invalid request time sleep: ":                  cfg.RequestTimeSleep < 500,
+		return nil, errors.New("telegram client is not authorized")
 	}
 
-	for msg, invalid := range validationErrors {
-		if invalid {
-			return nil, logger.Error("Parser.NewParser()", msg, invalid)
-		}
+	err := cfg.Validate()
+	if err != nil {
+		return nil, err
 	}
 
 	parser := &Parser{
 		client:                        client,
+		log:                           logger.With("component", "parser"),
 		data:                          nil,
 		chatID:                        -1,
 		chatUsername:                  cfg.ChatUsername,
 		countReadMessages:             int32(cfg.CountReadMessages),
 		skipLines:                     cfg.SkipLines,
-		sortIndex:                     -1,
-		isSort:                        cfg.IsSort,
-		isInvertSort:                  cfg.IsSortInvert,
+		mode:                          LINE_MODE,
+		duplicatePolicy:               DUPLICATE_NEWEST,
+		recordStart:                   cfg.RecordStart,
 		warehouseID:                   cfg.WarehouseID,
 		mainKeyword:                   cfg.MainKeyword,
-		sortKeyword:                   cfg.SortKeyword,
 		keywords:                      cfg.Keywords,
 		keyValues:                     cfg.KeyValues,
+		documentColumns:               cfg.DocumentColumns,
 		commandRequestWarehouseRoutes: cfg.CommandRequestData,
 		requestTimeSleep:              time.Duration(cfg.RequestTimeSleep) * time.Millisecond,
+		editQuietPeriod:               time.Duration(cfg.EditQuietPeriod) * time.Millisecond,
+		replyTimeout:                  time.Duration(cfg.ReplyTimeout) * time.Millisecond,
 	}
 
-	// The data after parsing is located in the same way as in the keywords array, so the sorting index will coincide with the sorting keyword
-	for i, keyword := range parser.keywords {
-		if keyword == parser.sortKeyword {
-			parser.sortIndex = i
+	if cfg.Mode != "" {
+		parser.mode = ParseMode(cfg.Mode)
+	}
+
+	switch parser.mode {
+	case LINE_MODE, BLOCK_MODE, TABLE_MODE:
+	default:
+		return nil, fmt.Errorf("unknown parse mode %q", cfg.Mode)
+	}
+
+	if cfg.DuplicatePolicy != "" {
+		parser.duplicatePolicy = DuplicatePolicy(cfg.DuplicatePolicy)
+	}
+
+	switch parser.duplicatePolicy {
+	case DUPLICATE_NEWEST, DUPLICATE_SUM, DUPLICATE_MAX, DUPLICATE_ERROR:
+	default:
+		return nil, fmt.Errorf("unknown duplicate policy %q", cfg.DuplicatePolicy)
+	}
+
+	if cfg.Filter != nil {
+		filter, err := newRowFilter(cfg.Filter, parser.keywords, parser.mainKeyword)
+		if err != nil {
+			return nil, fmt.Errorf("invalid filter: %w", err)
 		}
+		parser.filter = filter
 	}
 
-	if parser.sortIndex < 0 {
-		return nil, logger.Error("Parser.NewParser()", "Invalid sorting keyword: ", cfg.SortKeyword)
+	if cfg.FormatDrift != nil {
+		drift, err := newDriftDetector(cfg.FormatDrift, parser.keywords, parser.log)
+		if err != nil {
+			return nil, fmt.Errorf("create format drift detector: %w", err)
+		}
+		parser.drift = drift
+	}
+
+	if parser.recordStart == "" {
+		parser.recordStart = parser.mainKeyword
+	}
+
+	if parser.replyTimeout == 0 {
+		parser.replyTimeout = DEFAULT_REPLY_TIMEOUT
+	}
+
+	// The data after parsing is located in the same way as in the keywords array, so the sort indexes coincide with the sort fields
+	for _, key := range cfg.SortKeys() {
+		parser.sortKeys = append(parser.sortKeys, sortKey{index: slices.Index(parser.keywords, key.Field), desc: key.Desc})
 	}
 
 	chat, err := client.SearchPublicChat(parser.chatUsername)
 	if err != nil {
-		return nil, logger.Error("Parser.NewParser()", "Error search chat by chat username:\n", err)
+		return nil, fmt.Errorf("search chat %q: %w", parser.chatUsername, err)
 	}
 	parser.chatID = chat.Id
 
 	return parser, nil
 }
 
-func (p *Parser) Parse() ([][]int, error) {
-	err := p.sendRequestWarehouseRoutes()
+// Parse requests the report and parses the reply. The diagnostics describe every line of the reply,
+// they are returned with the parse errors too, nil only when the reply was not read.
+func (p *Parser) Parse(ctx context.Context) ([][]int, *Diagnostics, error) {
+	p.diagnostics = newDiagnostics()
+
+	// Subscribe before the request so the reply and its edits are not missed
+	events, unsubscribe := p.client.SubscribeMessages(p.chatID)
+	defer unsubscribe()
+
+	err := p.sendRequestWarehouseRoutes(ctx)
 	if err != nil {
-		return nil, logger.Error("Parser.Parse()", "Error request warehouse routes:\n", err)
+		reason := metrics.REASON_REQUEST
+		if errors.As(err, new(*telegramClient.FloodWaitError)) {
+			reason = metrics.REASON_FLOOD
+		}
+		metrics.ParseTotal.WithLabelValues(metrics.RESULT_FAILURE, reason).Inc()
+		return nil, nil, fmt.Errorf("request warehouse routes: %w", err)
 	}
 
-	time.Sleep(p.requestTimeSleep)
+	err = p.waitReply(ctx, events)
+	if err != nil {
+		metrics.ParseTotal.WithLabelValues(metrics.RESULT_FAILURE, metrics.REASON_REPLY).Inc()
+		return nil, nil, fmt.Errorf("wait reply: %w", err)
+	}
 
-	p.data, err = p.getDataWarehouseRoutes()
+	p.data, err = p.getDataWarehouseRoutes(ctx)
+	p.diagnostics.count()
 	if err != nil {
-		return nil, logger.Error("Parser.Parse()", "Error parse data routes:\n", err)
+		metrics.ParseTotal.WithLabelValues(metrics.RESULT_FAILURE, failureReason(err)).Inc()
+		return nil, p.diagnostics, fmt.Errorf("parse warehouse routes: %w", err)
 	}
 
-	if p.isSort {
+	if len(p.sortKeys) > 0 {
 		err = p.sortData()
 		if err != nil {
-			return nil, logger.Error("Parser.Parse()", "Error sort data routes:\n", err)
+			metrics.ParseTotal.WithLabelValues(metrics.RESULT_FAILURE, metrics.REASON_SORT).Inc()
+			return nil, p.diagnostics, fmt.Errorf("sort warehouse routes: %w", err)
 		}
 	}
 
-	return p.data, nil
+	metrics.ParseTotal.WithLabelValues(metrics.RESULT_SUCCESS, "").Inc()
+	metrics.ParsedRows.Add(float64(len(p.data)))
+
+	return p.data, p.diagnostics, nil
+}
+
+// SetReadOnly keeps the format baseline file as it is, e.g. for a one-off diagnostics run
+func (p *Parser) SetReadOnly(isReadOnly bool) {
+	if p.drift != nil {
+		p.drift.isReadOnly = isReadOnly
+	}
+}
+
+func (p *Parser) WarehouseID() string {
+	return p.warehouseID
+}
+
+func (p *Parser) MainKeyword() string {
+	return p.mainKeyword
 }
 
-func (p *Parser) sendRequestWarehouseRoutes() error {
+// Columns returns the names of the parsed columns, the rows returned by Parse are ordered the same way
+func (p *Parser) Columns() []string {
+	return append([]string(nil), p.keywords...)
+}
+
+func (p *Parser) sendRequestWarehouseRoutes(ctx context.Context) error {
+	p.log.DebugContext(ctx, "sending request command", "chat_id", p.chatID, "command", p.commandRequestWarehouseRoutes)
 	_, err := p.client.SendMessageText(p.chatID, p.commandRequestWarehouseRoutes)
 	if err != nil {
-		return logger.Error("Parser.sendRequestWarehouseRoutes()", "Error sending request warehouse routes:\n", err)
+		return fmt.Errorf("send command %q: %w", p.commandRequestWarehouseRoutes, err)
 	}
 
-	time.Sleep(p.requestTimeSleep)
+	err = sleep(ctx, p.requestTimeSleep)
+	if err != nil {
+		return err
+	}
 
+	p.log.DebugContext(ctx, "sending warehouse id", "chat_id", p.chatID, "warehouse_id", p.warehouseID)
 	_, err = p.client.SendMessageText(p.chatID, p.warehouseID)
 	if err != nil {
-		return logger.Error("Parser.sendRequestWarehouseRoutes()", "Error sending request warehouse routes:\n", err)
+		return fmt.Errorf("send warehouse id %q: %w", p.warehouseID, err)
 	}
 
 	return nil
 }
 
-func (p *Parser) getDataWarehouseRoutes() ([][]int, error) {
-	messages, err := p.getMessages()
+func (p *Parser) getDataWarehouseRoutes(ctx context.Context) ([][]int, error) {
+	messages, err := p.client.GetMessages(p.chatID, 0, p.countReadMessages)
 	if err != nil {
-		return nil, logger.Error("Parser.getDataWarehouseRoutes()", "Error getting messages:\n", err)
+		return nil, fmt.Errorf("get chat messages: %w", err)
 	}
 
 	if len(messages) == 0 {
-		return nil, logger.Error("Parser.getDataWarehouseRoutes()", "No messages")
+		return nil, ErrNoMessages
 	}
 
-	var routesData [][]int
-
+	// The messages are the newest first, the reply is everything the bot sent after our last request.
+	// The history holds the current content, so an edited reply is parsed in its final version.
+	var replies []*telegramClient.Message
 	for _, message := range messages {
-		lines := strings.Split(message, "\n")
-		skipLines := 0
+		if message.IsOutgoing {
+			break
+		}
+		replies = append(replies, message)
+	}
 
-		for i := 0; i < len(lines); i++ {
-			if skipLines > p.skipLines {
-				return nil, logger.Error("Parser.getDataWarehouseRoutes()", "The permissible skip line value has been exceeded: ", skipLines)
-			}
-			// Retrieve the number of the main keyword, checking for the presence of the main keyword
-			// If there are more missing lines than can be skipped, we exit the function
-			number, err := extractNumberAfterKeyword(lines[i], p.mainKeyword)
-			if err != nil {
-				skipLines++
-				logger.Warning("Parser.getDataWarehouseRoutes()", "Error extracting main keyword number from line: "+lines[i]+"\n", err)
-				continue
-			}
+	if len(replies) == 0 {
+		return nil, ErrNoMessages
+	}
 
-			// Checking whether a string should be added to the array
-			if p.isContainsValue(number) {
-				numbers, err := p.extractNumbers(lines[i])
-				if err != nil {
-					skipLines++
-					logger.Warning("Parser.getDataWarehouseRoutes()", "Error extracting numbers from line: "+lines[i]+"\n", err)
-					continue
-				}
+	// The reply is read in the order it was sent, so the rows of the newest message come last
+	slices.Reverse(replies)
 
-				routesData = append(routesData, numbers)
-			}
+	text := replyText(replies)
+	p.checkFormat(ctx, text)
+
+	var routesData [][]int
+
+	for _, message := range replies {
+		p.diagnostics.startMessage(message.ID)
+
+		rows, err := p.parseMessage(ctx, message)
+		if err != nil {
+			return nil, err
 		}
-	}
 
-	return routesData, nil
-}
+		routesData = append(routesData, rows...)
+	}
 
-func (p *Parser) getMessages() ([]string, error) {
-	messages, err := p.client.GetChatMessagesText(p.chatID, p.countReadMessages)
+	// A report split over several messages or an older report in the read messages may repeat a key
+	routesData, err = p.mergeDuplicates(ctx, routesData)
 	if err != nil {
-		return nil, logger.Error("Parser.getMessages()", "Error getting messages:\n", err)
+		return nil, err
 	}
 
-	return messages, nil
+	p.learnFormat(text, routesData)
+
+	return routesData, nil
 }
 
-func (p *Parser) sortData() error {
-	if p.isInvertSort {
-		for i := 0; i < len(p.data); i++ {
-			for j := i + 1; j < len(p.data); j++ {
-				if p.data[j][p.sortIndex] > p.data[i][p.sortIndex] {
-					p.data[i], p.data[j] = p.data[j], p.data[i]
-				}
-			}
-		}
-	} else {
-		for i := 0; i < len(p.data); i++ {
-			for j := i + 1; j < len(p.data); j++ {
-				if p.data[j][p.sortIndex] < p.data[i][p.sortIndex] {
-					p.data[i], p.data[j] = p.data[j], p.data[i]
-				}
-			}
-		}
+// learnFormat adds the reply to the format baseline, a reply without rows, e.g. "no routes", is not a sample of the report
+func (p *Parser) learnFormat(text string, rows [][]int) {
+	if p.drift == nil || text == "" || len(rows) == 0 {
+		return
 	}
 
-	return nil
+	p.drift.learn(text)
 }
 
-func (p *Parser) isContainsValue(value int) bool {
-	for i := 0; i < len(p.keyValues); i++ {
-		if p.keyValues[i] == value {
-			return true
+// replyText joins the texts of the reply messages, the documents have no text to check the format of
+func replyText(replies []*telegramClient.Message) string {
+	var texts []string
+	for _, message := range replies {
+		if message.Text != "" {
+			texts = append(texts, message.Text)
 		}
 	}
 
-	return false
+	return strings.Join(texts, "\n")
 }
 
-func (p *Parser) extractNumbers(text string) ([]int, error) {
-	numbers := make([]int, len(p.keywords))
-
-	var err error
+// checkFormat compares the text of the reply with the learned format, the replies made of documents only are not checked
+func (p *Parser) checkFormat(ctx context.Context, text string) {
+	if p.drift == nil || text == "" {
+		return
+	}
 
-	for i, keyword := range p.keywords {
-		if len(keyword) == 0 {
-			continue
-		}
+	p.diagnostics.Drift, p.diagnostics.DriftChanged = p.drift.check(text)
 
-		numbers[i], err = extractNumberAfterKeyword(text, keyword)
-		if err != nil {
-			return nil, err
-		}
+	if p.diagnostics.DriftChanged && p.diagnostics.Drift == nil {
+		p.log.InfoContext(ctx, "reply format is back to the learned one")
+	}
+	if p.diagnostics.Drift != nil {
+		p.log.WarnContext(ctx, "reply format changed", "reasons", p.diagnostics.Drift.Reasons)
 	}
-	return numbers, nil
 }
 
-func extractNumberAfterKeyword(text, keyword string) (int, error) {
-	start := strings.Index(text, keyword)
-	if start == -1 {
-		return 0, logger.Error("Parser.extractNumberAfterKeyword()", "Keyword not found in text: "+keyword)
+// lineLogLevel demotes the per-line warnings while the format change is reported as a whole
+func (p *Parser) lineLogLevel() slog.Level {
+	if p.diagnostics.Drift != nil {
+		return slog.LevelDebug
 	}
 
-	start += len(keyword)
+	return slog.LevelWarn
+}
 
-	for start < len(text) && (text[start] < '0' || text[start] > '9') {
-		start++
+// parseMessage reads the records of a message by the parse mode, all the modes produce the rows ordered as the keywords
+func (p *Parser) parseMessage(ctx context.Context, message *telegramClient.Message) ([][]int, error) {
+	// Some bots answer with a spreadsheet instead of a text
+	if message.Document != nil && isSupportedDocument(message.Document) {
+		return p.parseDocument(ctx, message.Document)
 	}
 
-	end := start
-	for end < len(text) && text[end] >= '0' && text[end] <= '9' {
-		end++
+	switch p.mode {
+	case BLOCK_MODE:
+		return p.parseBlocks(ctx, message.Text)
+	case TABLE_MODE:
+		return p.parseTables(ctx, message)
 	}
 
-	number, err := strconv.Atoi(text[start:end])
-	if err != nil {
-		return 0, logger.Error("Parser.extractNumberAfterKeyword()", "Unable to convert "+text[start:end]+" to number:\n", err)
+	return p.parseLines(ctx, message.Text)
+}
+
+func failureReason(err error) string {
+	switch {
+	case errors.Is(err, ErrNoMessages):
+		return metrics.REASON_EMPTY
+	case errors.Is(err, ErrSkipLinesExceeded):
+		return metrics.REASON_SKIP
+	case errors.As(err, new(*telegramClient.FloodWaitError)):
+		return metrics.REASON_FLOOD
+	case errors.Is(err, ErrMissingColumn), errors.Is(err, ErrUnsupportedDocument):
+		return metrics.REASON_DOCUMENT
+	case errors.Is(err, ErrDuplicateKey):
+		return metrics.REASON_DUPLICATE
 	}
 
-	return number, nil
+	return metrics.REASON_MESSAGES
+}
+
+// sortData orders the rows by the sort keys, the rows equal by every key keep the order of the report
+func (p *Parser) sortData() error {
+	slices.SortStableFunc(p.data, func(a, b []int) int {
+		for _, key := range p.sortKeys {
+			c := cmp.Compare(a[key.index], b[key.index])
+			if key.desc {
+				c = -c
+			}
+			if c != 0 {
+				return c
+			}
+		}
+
+		return 0
+	})
+
+	return nil
+}
+
+// sleep waits for the duration or until the context is done
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
 }
@@ -0,0 +1,67 @@
+package parser
+
+import (
+	"context"
+	"time"
+	"wb-assistance-logistic/telegramClient"
+)
+
+// Time to wait for the reply to settle when the quiet period is set and the timeout is not
+const DEFAULT_REPLY_TIMEOUT = 30 * time.Second
+
+// waitReply waits for the reply of the bot to settle. Many bots send a placeholder and edit it with the report,
+// so after the request time sleep it waits until there are no new messages or edits of the bot for the quiet period.
+// When the reply does not settle before the timeout its current version is parsed.
+func (p *Parser) waitReply(ctx context.Context, events <-chan *telegramClient.MessageEvent) error {
+	err := sleep(ctx, p.requestTimeSleep)
+	if err != nil {
+		return err
+	}
+
+	if p.editQuietPeriod <= 0 {
+		return nil
+	}
+
+	deadline := time.NewTimer(p.replyTimeout)
+	defer deadline.Stop()
+
+	// The quiet period counts from the first message of the bot, the events buffered during the sleep start it right away
+	quiet := time.NewTimer(p.editQuietPeriod)
+	quiet.Stop()
+	defer quiet.Stop()
+
+	outgoing := make(map[int64]bool)
+	changes := 0
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+
+		case event := <-events:
+			if event.IsOutgoing || outgoing[event.MessageID] {
+				outgoing[event.MessageID] = true
+				continue
+			}
+
+			changes++
+			p.log.DebugContext(ctx, "reply changed", "message_id", event.MessageID, "type", event.Type)
+
+			if !quiet.Stop() {
+				select {
+				case <-quiet.C:
+				default:
+				}
+			}
+			quiet.Reset(p.editQuietPeriod)
+
+		case <-quiet.C:
+			p.log.DebugContext(ctx, "reply settled", "changes", changes)
+			return nil
+
+		case <-deadline.C:
+			p.log.WarnContext(ctx, "reply did not settle in time, parsing its current version", "timeout", p.replyTimeout, "changes", changes)
+			return nil
+		}
+	}
+}
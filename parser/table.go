@@ -0,0 +1,71 @@
+package parser
+
+import (
+	"context"
+	"regexp"
+	"strings"
+	"unicode"
+	"wb-assistance-logistic/telegramClient"
+
+	"github.com/zelenin/go-tdlib/client"
+)
+
+// Cells of a monospace table without pipes are separated by tabs or at least two spaces
+var cellSeparator = regexp.MustCompile(`\t+| {2,}`)
+
+// parseTables reads the monospace tables sent in pre entities, a message without them is read as a single table.
+// The columns are found by the header the same way as in the documents, so a caption above the header is skipped.
+func (p *Parser) parseTables(ctx context.Context, message *telegramClient.Message) ([][]int, error) {
+	var tables []string
+
+	for _, entity := range message.Entities {
+		if entity.Type == client.TypeTextEntityTypePre || entity.Type == client.TypeTextEntityTypePreCode {
+			tables = append(tables, message.EntityText(entity))
+		}
+	}
+
+	if len(tables) == 0 {
+		tables = append(tables, message.Text)
+	}
+
+	var routesData [][]int
+
+	for _, text := range tables {
+		rows, err := p.parseTable(ctx, splitTable(text))
+		if err != nil {
+			return nil, err
+		}
+
+		routesData = append(routesData, rows...)
+	}
+
+	return routesData, nil
+}
+
+// splitTable splits the text table into cells by pipes, tabs or runs of two and more spaces,
+// so a single space keeps a multi-word header like "Склад назначения" in one cell.
+// A line without separators is a single cell, the border lines like "----+----" are dropped.
+func splitTable(text string) [][]string {
+	var table [][]string
+
+	for _, line := range strings.Split(text, "\n") {
+		if !strings.ContainsFunc(line, func(r rune) bool { return unicode.IsLetter(r) || unicode.IsDigit(r) }) {
+			continue
+		}
+
+		var cells []string
+		if strings.Contains(line, "|") {
+			cells = strings.Split(strings.Trim(strings.TrimSpace(line), "|"), "|")
+		} else {
+			cells = cellSeparator.Split(strings.TrimSpace(line), -1)
+		}
+
+		for i := range cells {
+			cells[i] = strings.TrimSpace(cells[i])
+		}
+
+		table = append(table, cells)
+	}
+
+	return table
+}
@@ -0,0 +1,135 @@
+package parser
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"reflect"
+	"testing"
+	"wb-assistance-logistic/telegramClient"
+
+	"github.com/zelenin/go-tdlib/client"
+)
+
+func newTestParser(mode ParseMode) *Parser {
+	return &Parser{
+		log:             slog.New(slog.NewTextHandler(io.Discard, nil)),
+		skipLines:       2,
+		mode:            mode,
+		duplicatePolicy: DUPLICATE_NEWEST,
+		recordStart:     "Парковка",
+		mainKeyword:     "Парковка",
+		keywords:        []string{"Парковка", "Склад назначения", "Коробок"},
+		diagnostics:     newDiagnostics(),
+	}
+}
+
+func TestSplitTable(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want [][]string
+	}{
+		{
+			name: "spaces",
+			text: "Парковка  Склад назначения  Коробок\n12        507               7",
+			want: [][]string{{"Парковка", "Склад назначения", "Коробок"}, {"12", "507", "7"}},
+		},
+		{
+			name: "tabs",
+			text: "Парковка\tСклад назначения\tКоробок\n12\t507\t7",
+			want: [][]string{{"Парковка", "Склад назначения", "Коробок"}, {"12", "507", "7"}},
+		},
+		{
+			name: "pipes and borders",
+			text: "| Парковка | Склад назначения | Коробок |\n|----------+------------------+---------|\n| 12 | 507 | 7 |",
+			want: [][]string{{"Парковка", "Склад назначения", "Коробок"}, {"12", "507", "7"}},
+		},
+		{
+			name: "single spaces keep one cell",
+			text: "Склад назначения",
+			want: [][]string{{"Склад назначения"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := splitTable(tt.text); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("splitTable() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// The same report laid out for every parse mode produces the same rows
+func TestParseModes(t *testing.T) {
+	want := [][]int{{12, 507, 7}, {14, 301, 0}, {3, 117, 25}}
+
+	table := "Парковка  Склад назначения  Коробок\n" +
+		"12        507               7\n" +
+		"14        301               0\n" +
+		"3         117               25"
+
+	tests := []struct {
+		name    string
+		mode    ParseMode
+		message *telegramClient.Message
+	}{
+		{
+			name: "line",
+			mode: LINE_MODE,
+			message: &telegramClient.Message{Text: "Маршруты склада\n" +
+				"Парковка 12, Склад назначения 507, Коробок 7\n" +
+				"Парковка 14, Склад назначения 301, Коробок 0\n" +
+				"Парковка 3, Склад назначения 117, Коробок 25"},
+		},
+		{
+			name: "block",
+			mode: BLOCK_MODE,
+			message: &telegramClient.Message{Text: "Маршруты склада\n\n" +
+				"Парковка 12\nСклад назначения: 507\nКоробок: 7\n\n" +
+				"Парковка 14\nСклад назначения: 301\nКоробок: 0\n\n" +
+				"Парковка 3\nСклад назначения: 117\nКоробок: 25"},
+		},
+		{
+			name:    "table",
+			mode:    TABLE_MODE,
+			message: &telegramClient.Message{Text: table},
+		},
+		{
+			name:    "table with a caption",
+			mode:    TABLE_MODE,
+			message: &telegramClient.Message{Text: "Маршруты склада\n\n" + table},
+		},
+		{
+			name: "table in a pre entity",
+			mode: TABLE_MODE,
+			message: &telegramClient.Message{
+				Text: "Маршруты склада\n" + table,
+				Entities: []*telegramClient.Entity{{
+					Type:   client.TypeTextEntityTypePre,
+					Offset: int32(len([]rune("Маршруты склада\n"))),
+					Length: int32(len([]rune(table))),
+				}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := newTestParser(tt.mode)
+
+			got, err := p.parseMessage(context.Background(), tt.message)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("parseMessage() = %v, want %v", got, want)
+			}
+			p.diagnostics.count()
+			if p.diagnostics.AcceptedLines != len(want) {
+				t.Errorf("accepted lines = %d, want %d", p.diagnostics.AcceptedLines, len(want))
+			}
+		})
+	}
+}
@@ -0,0 +1,238 @@
+package parser
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strconv"
+	"strings"
+)
+
+const (
+	xlsxWorkbook      = "xl/workbook.xml"
+	xlsxWorkbookRels  = "xl/_rels/workbook.xml.rels"
+	xlsxSharedStrings = "xl/sharedStrings.xml"
+
+	xlsxMaxColumns = 16384   // The last column is XFD
+	xlsxMaxRows    = 1048576 // The last row number
+)
+
+type xlsxWorkbookXML struct {
+	Sheets []struct {
+		Name string `xml:"name,attr"`
+		RID  string `xml:"http://schemas.openxmlformats.org/officeDocument/2006/relationships id,attr"`
+	} `xml:"sheets>sheet"`
+}
+
+type xlsxRelsXML struct {
+	Relationships []struct {
+		ID     string `xml:"Id,attr"`
+		Target string `xml:"Target,attr"`
+	} `xml:"Relationship"`
+}
+
+// xlsxText is a string item: a plain text or runs of a rich text
+type xlsxText struct {
+	T    string `xml:"t"`
+	Runs []struct {
+		T string `xml:"t"`
+	} `xml:"r"`
+}
+
+func (t *xlsxText) String() string {
+	if len(t.Runs) == 0 {
+		return t.T
+	}
+
+	var b strings.Builder
+	for _, run := range t.Runs {
+		b.WriteString(run.T)
+	}
+
+	return b.String()
+}
+
+type xlsxSharedStringsXML struct {
+	Items []xlsxText `xml:"si"`
+}
+
+type xlsxSheetXML struct {
+	Rows []struct {
+		Cells []struct {
+			Ref    string    `xml:"r,attr"`
+			Type   string    `xml:"t,attr"`
+			Value  string    `xml:"v"`
+			Inline *xlsxText `xml:"is"`
+		} `xml:"c"`
+	} `xml:"sheetData>row"`
+}
+
+// readXLSX reads the cells of the first sheet as text, the rows are padded to the rightmost filled cell
+func readXLSX(filePath string) ([][]string, error) {
+	archive, err := zip.OpenReader(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("open xlsx: %w", err)
+	}
+	defer archive.Close()
+
+	files := make(map[string]*zip.File, len(archive.File))
+	for _, file := range archive.File {
+		files[file.Name] = file
+	}
+
+	sheetPath, err := xlsxFirstSheet(files)
+	if err != nil {
+		return nil, err
+	}
+
+	var shared xlsxSharedStringsXML
+	if file, ok := files[xlsxSharedStrings]; ok {
+		err = decodeZipXML(file, &shared)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	file, ok := files[sheetPath]
+	if !ok {
+		return nil, fmt.Errorf("xlsx sheet %s not found", sheetPath)
+	}
+
+	var sheet xlsxSheetXML
+	err = decodeZipXML(file, &sheet)
+	if err != nil {
+		return nil, err
+	}
+
+	table := make([][]string, 0, len(sheet.Rows))
+	for _, row := range sheet.Rows {
+		var values []string
+
+		for i, cell := range row.Cells {
+			column := i
+			if cell.Ref != "" {
+				column, err = xlsxColumnIndex(cell.Ref)
+				if err != nil {
+					return nil, err
+				}
+			}
+
+			value := cell.Value
+			switch cell.Type {
+			case "s":
+				index, err := strconv.Atoi(cell.Value)
+				if err != nil || index < 0 || index >= len(shared.Items) {
+					return nil, fmt.Errorf("xlsx cell %s: invalid shared string %q", cell.Ref, cell.Value)
+				}
+				value = shared.Items[index].String()
+			case "inlineStr":
+				if cell.Inline != nil {
+					value = cell.Inline.String()
+				}
+			}
+
+			for len(values) <= column {
+				values = append(values, "")
+			}
+			values[column] = value
+		}
+
+		table = append(table, values)
+	}
+
+	return table, nil
+}
+
+func xlsxFirstSheet(files map[string]*zip.File) (string, error) {
+	file, ok := files[xlsxWorkbook]
+	if !ok {
+		return "", errors.New("xlsx workbook not found")
+	}
+
+	var workbook xlsxWorkbookXML
+	err := decodeZipXML(file, &workbook)
+	if err != nil {
+		return "", err
+	}
+
+	if len(workbook.Sheets) == 0 {
+		return "", errors.New("xlsx workbook has no sheets")
+	}
+
+	file, ok = files[xlsxWorkbookRels]
+	if !ok {
+		return "", errors.New("xlsx workbook relationships not found")
+	}
+
+	var rels xlsxRelsXML
+	err = decodeZipXML(file, &rels)
+	if err != nil {
+		return "", err
+	}
+
+	for _, rel := range rels.Relationships {
+		if rel.ID != workbook.Sheets[0].RID {
+			continue
+		}
+
+		// Targets are relative to the xl directory, some writers use absolute paths
+		if strings.HasPrefix(rel.Target, "/") {
+			return strings.TrimPrefix(rel.Target, "/"), nil
+		}
+		return path.Join("xl", rel.Target), nil
+	}
+
+	return "", fmt.Errorf("xlsx sheet %q relationship not found", workbook.Sheets[0].Name)
+}
+
+func decodeZipXML(file *zip.File, v any) error {
+	if file.UncompressedSize64 > maxDocumentSize {
+		return fmt.Errorf("%w: %s is over %d bytes", ErrDocumentTooLarge, file.Name, maxDocumentSize)
+	}
+
+	reader, err := file.Open()
+	if err != nil {
+		return fmt.Errorf("open %s: %w", file.Name, err)
+	}
+	defer reader.Close()
+
+	err = xml.NewDecoder(io.LimitReader(reader, maxDocumentSize)).Decode(v)
+	if err != nil {
+		return fmt.Errorf("decode %s: %w", file.Name, err)
+	}
+
+	return nil
+}
+
+// xlsxColumnIndex converts the letters of a cell reference, e.g. "AB12", to a zero-based column index,
+// the column must be within XFD and the row number within the sheet limit
+func xlsxColumnIndex(ref string) (int, error) {
+	column := 0
+	letters := 0
+
+	for _, r := range ref {
+		if r < 'A' || r > 'Z' {
+			break
+		}
+		column = column*26 + int(r-'A'+1)
+		letters++
+
+		if column > xlsxMaxColumns {
+			return 0, fmt.Errorf("xlsx cell reference %q is beyond the last column", ref)
+		}
+	}
+
+	if letters == 0 {
+		return 0, fmt.Errorf("invalid xlsx cell reference %q", ref)
+	}
+
+	row, err := strconv.Atoi(ref[letters:])
+	if err != nil || row < 1 || row > xlsxMaxRows || ref[letters] == '+' {
+		return 0, fmt.Errorf("invalid xlsx cell reference %q", ref)
+	}
+
+	return column - 1, nil
+}
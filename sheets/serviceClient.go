@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"golang.org/x/oauth2"
 	"golang.org/x/oauth2/google"
 	"google.golang.org/api/googleapi"
@@ -33,7 +34,7 @@ func NewClient() *Client {
 
 func (c *Client) AuthByToken(ctx context.Context, token *oauth2.Token) error {
 	if token == nil {
-		return errors.New("token cannot be nil\n")
+		return errors.New("token cannot be nil")
 	}
 
 	c.token = token
@@ -66,7 +67,7 @@ func (c *Client) AuthByCredentials(ctx context.Context, credentials string, scop
 	authURL := c.config.AuthCodeURL("state-token", oauth2.AccessTypeOffline)
 	authCode := authHandle(authURL)
 	if authCode == "" {
-		return errors.New("failed to get auth code\n")
+		return errors.New("failed to get auth code")
 	}
 
 	c.token, err = c.config.Exchange(ctx, authCode)
@@ -123,11 +124,11 @@ func (c *Client) AuthByJSONToken(ctx context.Context, tokenPath string) error {
 
 func (c *Client) RefreshToken(ctx context.Context) error {
 	if !c.isAuth {
-		return errors.New("client is not authorized\n")
+		return errors.New("client is not authorized")
 	}
 
 	if c.config == nil {
-		return errors.New("invalid config client\n")
+		return errors.New("invalid config client")
 	}
 
 	token := &oauth2.Token{
@@ -139,7 +140,7 @@ func (c *Client) RefreshToken(ctx context.Context) error {
 	var err error
 	c.token, err = tokenSource.Token()
 	if err != nil {
-		return errors.New("error getting new token:\n" + err.Error())
+		return fmt.Errorf("get new token: %w", err)
 	}
 
 	return nil
@@ -154,7 +155,7 @@ func (c *Client) CheckValidToken() (bool, error) {
 			}
 		}
 
-		return false, errors.New("failed to check token validity:\n" + err.Error())
+		return false, fmt.Errorf("check token validity: %w", err)
 	}
 
 	return true, nil
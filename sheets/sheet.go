@@ -21,10 +21,10 @@ func NewSheet(id string) *Sheet {
 
 func NewSheetByService(id string, service ServiceInterface) (*Sheet, error) {
 	if service == nil {
-		return nil, errors.New("internal is nil\n")
+		return nil, errors.New("internal is nil")
 	}
 	if !service.IsAuth() {
-		return nil, errors.New("internal is not authorize\n")
+		return nil, errors.New("internal is not authorize")
 	}
 
 	return &Sheet{
@@ -36,7 +36,7 @@ func NewSheetByService(id string, service ServiceInterface) (*Sheet, error) {
 
 func (s *Sheet) SetService(service ServiceInterface) error {
 	if !service.IsAuth() {
-		return errors.New("internal is not authorized\n")
+		return errors.New("internal is not authorized")
 	}
 
 	s.service = service
@@ -45,9 +45,13 @@ func (s *Sheet) SetService(service ServiceInterface) error {
 	return nil
 }
 
+func (s *Sheet) IsAuth() bool {
+	return s.service != nil && s.service.IsAuth()
+}
+
 func (s *Sheet) Update(pageName string, startIndex string, data [][]interface{}) error {
 	if s.service == nil {
-		return errors.New("there is no connection to internal\n")
+		return errors.New("there is no connection to internal")
 	}
 
 	vr := &sheets.ValueRange{
@@ -64,7 +68,7 @@ func (s *Sheet) Update(pageName string, startIndex string, data [][]interface{})
 
 func (s *Sheet) Append(pageName string, startIndex string, data [][]interface{}) error {
 	if s.service == nil {
-		return errors.New("there is no connection to internal\n")
+		return errors.New("there is no connection to internal")
 	}
 
 	valueRange := &sheets.ValueRange{
@@ -81,7 +85,7 @@ func (s *Sheet) Append(pageName string, startIndex string, data [][]interface{})
 
 func (s *Sheet) Clear(pageName string, startIndex string) error {
 	if s.service == nil {
-		return errors.New("there is no connection to internal\n")
+		return errors.New("there is no connection to internal")
 	}
 
 	_, err := s.googleService.Spreadsheets.Values.Clear(s.id, pageName+"!"+startIndex, &sheets.ClearValuesRequest{}).Do()
@@ -0,0 +1,62 @@
+package snapshot
+
+import (
+	"fmt"
+	"sort"
+)
+
+type Query struct {
+	KeyFrom *int
+	KeyTo   *int
+	SortBy  string
+	Desc    bool
+}
+
+func (s *Snapshot) ColumnIndex(name string) int {
+	for i, column := range s.Columns {
+		if column == name {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// Apply returns a copy of the snapshot with the rows filtered by the key range and sorted by the column, the original is left untouched
+func (s *Snapshot) Apply(q Query) (*Snapshot, error) {
+	result := *s
+	result.Rows = make([][]int, 0, len(s.Rows))
+
+	keyIndex := s.ColumnIndex(s.KeyColumn)
+	if keyIndex < 0 && (q.KeyFrom != nil || q.KeyTo != nil) {
+		return nil, fmt.Errorf("key column %q is not in the snapshot", s.KeyColumn)
+	}
+
+	for _, row := range s.Rows {
+		if q.KeyFrom != nil && row[keyIndex] < *q.KeyFrom {
+			continue
+		}
+		if q.KeyTo != nil && row[keyIndex] > *q.KeyTo {
+			continue
+		}
+		result.Rows = append(result.Rows, row)
+	}
+
+	if q.SortBy == "" {
+		return &result, nil
+	}
+
+	sortIndex := s.ColumnIndex(q.SortBy)
+	if sortIndex < 0 {
+		return nil, fmt.Errorf("unknown sort field %q", q.SortBy)
+	}
+
+	sort.SliceStable(result.Rows, func(i, j int) bool {
+		if q.Desc {
+			return result.Rows[i][sortIndex] > result.Rows[j][sortIndex]
+		}
+		return result.Rows[i][sortIndex] < result.Rows[j][sortIndex]
+	})
+
+	return &result, nil
+}
@@ -0,0 +1,200 @@
+package snapshot
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+const DEFAULT_HISTORY_SIZE = 100
+
+// Snapshot is the result of one successful parse of a warehouse report.
+// Rows are ordered like Columns, KeyColumn is the column the rows are identified by (the main keyword).
+type Snapshot struct {
+	TickID      string    `json:"tick_id"`
+	WarehouseID string    `json:"warehouse_id"`
+	Time        time.Time `json:"time"`
+	KeyColumn   string    `json:"key_column"`
+	Columns     []string  `json:"columns"`
+	Rows        [][]int   `json:"rows"`
+}
+
+type TickRecord struct {
+	TickID      string    `json:"tick_id"`
+	WarehouseID string    `json:"warehouse_id"`
+	Start       time.Time `json:"start"`
+	DurationMs  int64     `json:"duration_ms"`
+	Success     bool      `json:"success"`
+	Error       string    `json:"error,omitempty"`
+	Rows        int       `json:"rows"`
+}
+
+type state struct {
+	Latest  map[string]*Snapshot `json:"latest"`
+	History []TickRecord         `json:"history"`
+}
+
+// Store keeps the latest snapshot per warehouse and a bounded tick history in memory,
+// when a path is set every change is also persisted to a JSON file and loaded back on start.
+type Store struct {
+	mu          sync.RWMutex
+	path        string
+	historySize int
+	state       state
+}
+
+func NewStore(path string, historySize int) (*Store, error) {
+	if historySize <= 0 {
+		historySize = DEFAULT_HISTORY_SIZE
+	}
+
+	s := &Store{
+		path:        path,
+		historySize: historySize,
+		state: state{
+			Latest: map[string]*Snapshot{},
+		},
+	}
+
+	if path == "" {
+		return s, nil
+	}
+
+	file, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read snapshots file: %w", err)
+	}
+
+	err = json.Unmarshal(file, &s.state)
+	if err != nil {
+		return nil, fmt.Errorf("unmarshal snapshots file: %w", err)
+	}
+
+	if s.state.Latest == nil {
+		s.state.Latest = map[string]*Snapshot{}
+	}
+	s.trimHistory()
+
+	return s, nil
+}
+
+func (s *Store) Put(snapshot *Snapshot) error {
+	if snapshot == nil {
+		return errors.New("snapshot can not be nil")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.state.Latest[snapshot.WarehouseID] = snapshot
+
+	return s.save()
+}
+
+func (s *Store) AddTick(record TickRecord) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.state.History = append(s.state.History, record)
+	s.trimHistory()
+
+	return s.save()
+}
+
+func (s *Store) Latest(warehouseID string) (*Snapshot, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	snapshot, ok := s.state.Latest[warehouseID]
+	return snapshot, ok
+}
+
+func (s *Store) Warehouses() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	ids := make([]string, 0, len(s.state.Latest))
+	for id := range s.state.Latest {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	return ids
+}
+
+// LastUpdate returns the time of the newest snapshot, zero when there is none
+func (s *Store) LastUpdate() time.Time {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var last time.Time
+	for _, snapshot := range s.state.Latest {
+		if snapshot.Time.After(last) {
+			last = snapshot.Time
+		}
+	}
+
+	return last
+}
+
+// History returns up to limit newest tick records, newest first, limit <= 0 returns all of them
+func (s *Store) History(limit int) []TickRecord {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	n := len(s.state.History)
+	if limit <= 0 || limit > n {
+		limit = n
+	}
+
+	records := make([]TickRecord, 0, limit)
+	for i := n - 1; i >= n-limit; i-- {
+		records = append(records, s.state.History[i])
+	}
+
+	return records
+}
+
+func (s *Store) trimHistory() {
+	if over := len(s.state.History) - s.historySize; over > 0 {
+		s.state.History = append([]TickRecord(nil), s.state.History[over:]...)
+	}
+}
+
+// save writes the state to a temporary file and renames it, so a crash never leaves a truncated file
+func (s *Store) save() error {
+	if s.path == "" {
+		return nil
+	}
+
+	data, err := json.Marshal(s.state)
+	if err != nil {
+		return fmt.Errorf("marshal snapshots: %w", err)
+	}
+
+	err = os.MkdirAll(filepath.Dir(s.path), 0o755)
+	if err != nil {
+		return fmt.Errorf("create snapshots directory: %w", err)
+	}
+
+	tmp := s.path + ".tmp"
+	err = os.WriteFile(tmp, data, 0o644)
+	if err != nil {
+		return fmt.Errorf("write snapshots file: %w", err)
+	}
+
+	err = os.Rename(tmp, s.path)
+	if err != nil {
+		return fmt.Errorf("rename snapshots file: %w", err)
+	}
+
+	return nil
+}
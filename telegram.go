@@ -0,0 +1,117 @@
+package main
+
+import (
+	"fmt"
+	"time"
+	"wb-assistance-logistic/config"
+	"wb-assistance-logistic/controlBot"
+	"wb-assistance-logistic/logger"
+	"wb-assistance-logistic/telegramClient"
+)
+
+// newTelegramClient creates the telegram client by the config, it is not authorized yet
+func newTelegramClient(cfg *config.Config) (*telegramClient.Client, error) {
+	inputHandler, err := newAuthInputHandler(cfg.TelegramClient.Auth, cfg.Control)
+	if err != nil {
+		return nil, fmt.Errorf("create telegram auth input handler: %w", err)
+	}
+
+	client, err := telegramClient.NewClientByParameters(&telegramClient.ClientParameters{
+		ApiId:          int32(cfg.TelegramClient.Id),
+		ApiHash:        cfg.TelegramClient.Hash,
+		FilesDirectory: cfg.TelegramClient.FilesDirectory,
+	}, inputHandler)
+	if err != nil {
+		return nil, fmt.Errorf("create telegram client: %w", err)
+	}
+
+	if auth := cfg.TelegramClient.Auth; auth != nil {
+		err = client.SetLoginMode(telegramClient.LoginMode(auth.Mode), auth.QRCodeFile)
+		if err != nil {
+			return nil, fmt.Errorf("set telegram login mode: %w", err)
+		}
+	}
+	if rate := cfg.TelegramClient.RateLimit; rate != nil {
+		err = client.SetRateLimit(telegramClient.RateLimit{
+			ChatInterval: time.Duration(rate.ChatInterval) * time.Millisecond,
+			ReadRetries:  rate.ReadRetries,
+			MaxRetryWait: time.Duration(rate.MaxRetryWait) * time.Millisecond,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("set telegram rate limit: %w", err)
+		}
+	}
+	if len(cfg.TelegramClient.Proxies) > 0 {
+		proxies := make([]*telegramClient.Proxy, 0, len(cfg.TelegramClient.Proxies))
+		for _, proxy := range cfg.TelegramClient.Proxies {
+			proxies = append(proxies, &telegramClient.Proxy{
+				Type:     telegramClient.ProxyType(proxy.Type),
+				Server:   proxy.Server,
+				Port:     int32(proxy.Port),
+				Username: proxy.Username,
+				Password: proxy.Password,
+				HttpOnly: proxy.HttpOnly,
+				Secret:   proxy.Secret,
+			})
+		}
+
+		err = client.SetProxies(proxies, time.Duration(cfg.TelegramClient.ProxyCheckInterval)*time.Millisecond)
+		if err != nil {
+			return nil, fmt.Errorf("set telegram proxies: %w", err)
+		}
+	}
+
+	err = telegramClient.SetTelegramClientLogsVerboseLevel(telegramClient.LogsVerboseLevel(cfg.TelegramClient.LogLevel))
+	if err != nil {
+		return nil, fmt.Errorf("set telegram client logs verbose level: %w", err)
+	}
+	logger.Info("telegram client logs verbose level set", "level", cfg.TelegramClient.LogLevel)
+
+	return client, nil
+}
+
+// newAuthInputHandler creates the handler chosen by cfg.Handler, the console one by default.
+// The phone number and the password references are read by a SecretAuthInputHandler in front of it.
+func newAuthInputHandler(cfg *config.TelegramAuth, control *config.Control) (telegramClient.AuthInputHandler, error) {
+	if cfg == nil {
+		return &telegramClient.ConsoleAuthInputHandler{}, nil
+	}
+
+	var handler telegramClient.AuthInputHandler
+	var err error
+
+	switch cfg.Handler {
+	case "", telegramClient.CONSOLE_AUTH_INPUT:
+		handler = &telegramClient.ConsoleAuthInputHandler{}
+	case telegramClient.FILE_AUTH_INPUT:
+		handler, err = telegramClient.NewFileAuthInputHandler(cfg.DropDirectory)
+	case telegramClient.HTTP_AUTH_INPUT:
+		handler, err = telegramClient.NewHTTPAuthInputHandler(cfg.HTTPAddress)
+	case telegramClient.BOT_AUTH_INPUT:
+		if control == nil {
+			return nil, fmt.Errorf("%s auth input requires the control section", telegramClient.BOT_AUTH_INPUT)
+		}
+
+		var bot *controlBot.Bot
+		bot, err = controlBot.NewBot(control.Token)
+		if err == nil {
+			handler, err = telegramClient.NewBotAuthInputHandler(bot, control.AdminUsername)
+		}
+	default:
+		return nil, fmt.Errorf("unknown auth input handler %q", cfg.Handler)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("create %s auth input handler: %w", cfg.Handler, err)
+	}
+
+	if cfg.PhoneNumber != "" || cfg.Password != "" {
+		handler = &telegramClient.SecretAuthInputHandler{
+			PhoneNumber: cfg.PhoneNumber,
+			Password:    cfg.Password,
+			Next:        handler,
+		}
+	}
+
+	return handler, nil
+}
@@ -1,5 +1,13 @@
 package telegramClient
 
+// Names of the auth input handlers in the config
+const (
+	CONSOLE_AUTH_INPUT = "console"
+	FILE_AUTH_INPUT    = "file"
+	HTTP_AUTH_INPUT    = "http"
+	BOT_AUTH_INPUT     = "bot"
+)
+
 type AuthInputHandler interface {
 	InputPhoneNumber() (string, error)
 	InputCode() (string, error)
@@ -0,0 +1,156 @@
+package telegramClient
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/zelenin/go-tdlib/client"
+)
+
+// Time after which the QR code state is checked again when no update has come
+const QR_CODE_WAIT_TIMEOUT = time.Minute
+
+// authorizer answers the authorization states of a TDLib instance during client.NewClient
+type authorizer struct {
+	client    *Client
+	lastState string
+	lastLink  string
+	loggedOut bool          // The logging out state was seen, TDLib passes the closing state after it
+	proxyDone chan struct{} // Stops the proxy monitor of the instance
+}
+
+func (a *authorizer) Handle(td *client.Client, state client.AuthorizationState) error {
+	c := a.client
+	a.lastState = state.AuthorizationStateType()
+	c.log.Debug("authorization state", "state", a.lastState)
+
+	switch state := state.(type) {
+	case *client.AuthorizationStateWaitTdlibParameters:
+		_, err := td.SetTdlibParameters(c.parameters)
+		if err != nil {
+			return err
+		}
+
+		// The proxies are needed before the authorization can reach Telegram
+		ids, err := c.applyProxies(td)
+		if err != nil {
+			return err
+		}
+		go c.monitorProxies(td, ids, a.proxyDone)
+
+		return nil
+
+	case *client.AuthorizationStateWaitPhoneNumber:
+		if c.loginMode == QR_LOGIN {
+			_, err := td.RequestQrCodeAuthentication(&client.RequestQrCodeAuthenticationRequest{})
+			return err
+		}
+
+		c.requireInput(a.lastState)
+		phoneNumber, err := c.readInput("phone number", c.inputHandler.InputPhoneNumber)
+		if err != nil {
+			return err
+		}
+		_, err = td.SetAuthenticationPhoneNumber(&client.SetAuthenticationPhoneNumberRequest{
+			PhoneNumber: phoneNumber,
+			Settings:    &client.PhoneNumberAuthenticationSettings{},
+		})
+		return a.retryable(err)
+
+	case *client.AuthorizationStateWaitOtherDeviceConfirmation:
+		return a.waitQRCode(td, state.Link)
+
+	case *client.AuthorizationStateWaitCode:
+		c.requireInput(a.lastState)
+		code, err := c.readInput("authentication code", c.inputHandler.InputCode)
+		if err != nil {
+			return err
+		}
+		_, err = td.CheckAuthenticationCode(&client.CheckAuthenticationCodeRequest{
+			Code: code,
+		})
+		return a.retryable(err)
+
+	case *client.AuthorizationStateWaitPassword:
+		c.requireInput(a.lastState)
+		password, err := c.readInput("password", c.inputHandler.InputPassword)
+		if err != nil {
+			return err
+		}
+		_, err = td.CheckAuthenticationPassword(&client.CheckAuthenticationPasswordRequest{
+			Password: password,
+		})
+		return a.retryable(err)
+
+	case *client.AuthorizationStateLoggingOut:
+		c.log.Warn("telegram session is logging out")
+		c.setState(AUTH_STATE_LOGGING_OUT)
+		a.loggedOut = true
+		return client.ErrNotSupportedAuthorizationState
+
+	case *client.AuthorizationStateReady, *client.AuthorizationStateClosing, *client.AuthorizationStateClosed:
+		return nil
+	}
+
+	return client.ErrNotSupportedAuthorizationState
+}
+
+func (a *authorizer) Close() {}
+
+// retryable keeps the instance on a rejected input, e.g. a mistyped code, so the same state is asked again
+func (a *authorizer) retryable(err error) error {
+	var responseErr client.ResponseError
+	if errors.As(err, &responseErr) && responseErr.Err.Code == http.StatusBadRequest {
+		a.client.log.Warn("telegram rejected the login input", "state", a.lastState, "error", responseErr.Err.Message)
+		return nil
+	}
+
+	return err
+}
+
+// waitQRCode shows the login link and waits until TDLib rotates it or the login moves on,
+// the authorization loop asks the state again right after Handle returns
+func (a *authorizer) waitQRCode(td *client.Client, link string) error {
+	c := a.client
+
+	if link != a.lastLink {
+		if a.lastLink == "" {
+			c.requireInput(a.lastState)
+		}
+		a.lastLink = link
+
+		err := c.showQRCode(link)
+		if err != nil {
+			return err
+		}
+	}
+
+	listener := td.GetListener()
+	defer listener.Close()
+
+	// The state could have changed before the listener was added
+	state, err := td.GetAuthorizationState()
+	if err != nil {
+		return err
+	}
+	if current, ok := state.(*client.AuthorizationStateWaitOtherDeviceConfirmation); !ok || current.Link != link {
+		return nil
+	}
+
+	timer := time.NewTimer(QR_CODE_WAIT_TIMEOUT)
+	defer timer.Stop()
+
+	for {
+		select {
+		case update := <-listener.Updates:
+			if _, ok := update.(*client.UpdateAuthorizationState); ok {
+				return nil
+			}
+		case <-timer.C:
+			return nil
+		case <-c.chanAuthClose:
+			return errClientClosed
+		}
+	}
+}
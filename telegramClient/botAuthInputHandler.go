@@ -0,0 +1,110 @@
+package telegramClient
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+	"time"
+	"unicode"
+	"wb-assistance-logistic/controlBot"
+	"wb-assistance-logistic/logger"
+)
+
+const BOT_POLL_TIMEOUT = 30 * time.Second
+
+// BotAuthInputHandler asks the admin for the values through the control bot.
+// The admin has to write to the bot first, a bot can not start a chat by itself.
+type BotAuthInputHandler struct {
+	bot           *controlBot.Bot
+	adminUsername string
+	chatID        int64
+	log           *slog.Logger
+}
+
+func NewBotAuthInputHandler(bot *controlBot.Bot, adminUsername string) (*BotAuthInputHandler, error) {
+	if bot == nil {
+		return nil, errors.New("bot can not be nil")
+	}
+
+	adminUsername = strings.TrimPrefix(adminUsername, "@")
+	if adminUsername == "" {
+		return nil, errors.New("admin username can not be empty")
+	}
+
+	return &BotAuthInputHandler{
+		bot:           bot,
+		adminUsername: adminUsername,
+		log:           logger.With("component", "telegram_auth"),
+	}, nil
+}
+
+func (h *BotAuthInputHandler) InputPhoneNumber() (string, error) {
+	return h.ask("Telegram login required. Send the phone number of the account.")
+}
+
+// InputCode accepts the code with any separators: Telegram expires a login code sent in a message unchanged
+func (h *BotAuthInputHandler) InputCode() (string, error) {
+	answer, err := h.ask("Send the login code with separators between the digits, e.g. 1-2-3-4-5. Telegram expires a code sent unchanged.")
+	if err != nil {
+		return "", err
+	}
+
+	return strings.Map(func(r rune) rune {
+		if unicode.IsDigit(r) {
+			return r
+		}
+		return -1
+	}, answer), nil
+}
+
+func (h *BotAuthInputHandler) InputPassword() (string, error) {
+	return h.ask("Send the two-step verification password. Delete the message after the login.")
+}
+
+// ask sends the prompt to the admin and returns the text of the next admin message
+func (h *BotAuthInputHandler) ask(prompt string) (string, error) {
+	ctx := context.Background()
+
+	// The messages sent before the prompt are not answers to it
+	_, err := h.bot.GetUpdates(ctx, 0)
+	if err != nil {
+		return "", err
+	}
+
+	if h.chatID != 0 {
+		_, err = h.bot.SendMessage(ctx, h.chatID, prompt)
+		if err != nil {
+			return "", err
+		}
+	} else {
+		h.log.Warn("waiting for the admin to write to the control bot", "admin", h.adminUsername)
+	}
+
+	for {
+		updates, err := h.bot.GetUpdates(ctx, BOT_POLL_TIMEOUT)
+		if err != nil {
+			return "", err
+		}
+
+		for _, update := range updates {
+			message := update.Message
+			if message == nil || message.From == nil || message.Chat == nil || !strings.EqualFold(message.From.Username, h.adminUsername) {
+				continue
+			}
+
+			if h.chatID == 0 {
+				h.chatID = message.Chat.ID
+				_, err = h.bot.SendMessage(ctx, h.chatID, prompt)
+				if err != nil {
+					return "", err
+				}
+				continue
+			}
+
+			if text := strings.TrimSpace(message.Text); text != "" {
+				return text, nil
+			}
+		}
+	}
+}
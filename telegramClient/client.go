@@ -2,9 +2,13 @@ package telegramClient
 
 import (
 	"errors"
+	"fmt"
 	"github.com/zelenin/go-tdlib/client"
-	"log"
+	"log/slog"
+	"sync"
+	"sync/atomic"
 	"time"
+	"wb-assistance-logistic/logger"
 )
 
 type ClientParameters struct {
@@ -25,15 +29,28 @@ type ClientParameters struct {
 }
 
 type Client struct {
-	id            int32
-	hash          string
-	client        *client.Client
-	parameters    *client.SetTdlibParametersRequest
-	inputHandler  AuthInputHandler
-	user          *client.User
-	chanAuthClose chan struct{}
-	chanAuthReady chan bool
-	isAuth        bool
+	id                 int32
+	hash               string
+	client             *client.Client
+	parameters         *client.SetTdlibParametersRequest
+	inputHandler       AuthInputHandler
+	loginMode          LoginMode
+	qrCodeFile         string
+	limiter            *limiter
+	proxies            []*Proxy
+	proxyCheckInterval time.Duration
+	user               *client.User
+	chanAuthClose      chan struct{}
+	chanAuthReady      chan bool
+	isAuth             atomic.Bool
+	log                *slog.Logger
+
+	// mu guards the TDLib instance replaced on reconnect, the reported state and the subscribers
+	mu          sync.RWMutex
+	state       AuthState
+	onState     func(AuthState)
+	subscribers map[*subscriber]struct{}
+	closeOnce   sync.Once
 }
 
 func NewClient(id int32, hash string) *Client {
@@ -56,26 +73,40 @@ func NewClient(id int32, hash string) *Client {
 			ApplicationVersion:  "1.0.0",
 		},
 		inputHandler:  &ConsoleAuthInputHandler{},
+		loginMode:     PHONE_LOGIN,
+		limiter:       newLimiter(DefaultRateLimit),
 		user:          nil,
 		chanAuthClose: make(chan struct{}),
-		chanAuthReady: make(chan bool),
-		isAuth:        false,
+		chanAuthReady: make(chan bool, 1),
+		onState:       func(AuthState) {},
+		subscribers:   map[*subscriber]struct{}{},
+		log:           logger.With("component", "telegram_client"),
 	}
 }
 
-func NewClientByParameters(parameters *ClientParameters) (*Client, error) {
+// NewClientByParameters creates a client asking the login values from the input handler, nil means the console
+func NewClientByParameters(parameters *ClientParameters, inputHandler AuthInputHandler) (*Client, error) {
 	c := &Client{}
 	err := c.SetParameters(parameters)
 	if err != nil {
 		return nil, err
 	}
+
+	if inputHandler == nil {
+		inputHandler = &ConsoleAuthInputHandler{}
+	}
+
 	c.id = parameters.ApiId
 	c.hash = parameters.ApiHash
-	c.inputHandler = &ConsoleAuthInputHandler{}
+	c.inputHandler = inputHandler
+	c.loginMode = PHONE_LOGIN
+	c.limiter = newLimiter(DefaultRateLimit)
 	c.user = nil
 	c.chanAuthClose = make(chan struct{})
-	c.chanAuthReady = make(chan bool)
-	c.isAuth = false
+	c.chanAuthReady = make(chan bool, 1)
+	c.onState = func(AuthState) {}
+	c.subscribers = map[*subscriber]struct{}{}
+	c.log = logger.With("component", "telegram_client")
 
 	return c, nil
 }
@@ -128,79 +159,33 @@ func (c *Client) SetParameters(parameters *ClientParameters) error {
 	return nil
 }
 
-func (c *Client) Auth() error {
-	authorizer := client.ClientAuthorizer()
-
-	go func() {
-		defer close(c.chanAuthReady)
-		time.Sleep(500 * time.Millisecond)
-
-		for {
-			select {
-			case state, ok := <-authorizer.State:
-				if !ok {
-					var err error
-					c.user, err = c.GetMe()
-					if err != nil {
-						c.isAuth = false
-					} else {
-						c.isAuth = true
-					}
-
-					c.chanAuthReady <- c.isAuth
-					return
-				}
-
-				switch state.AuthorizationStateType() {
-				case client.TypeAuthorizationStateWaitPhoneNumber:
-					phoneNumber, err := c.inputHandler.InputPhoneNumber()
-					if err != nil {
-						log.Println(err)
-						continue
-					}
-					authorizer.PhoneNumber <- phoneNumber
-
-				case client.TypeAuthorizationStateWaitCode:
-					code, err := c.inputHandler.InputCode()
-					if err != nil {
-						log.Println(err)
-						continue
-					}
-					authorizer.Code <- code
-
-				case client.TypeAuthorizationStateWaitPassword:
-					password, err := c.inputHandler.InputPassword()
-					if err != nil {
-						log.Println(err)
-						continue
-					}
-					authorizer.Password <- password
-
-				case client.TypeAuthorizationStateLoggingOut:
-					log.Println("Authorization state TDLib is LoggingOut. Need create new TDLib session")
-					return
-				case client.TypeAuthorizationStateReady:
-					authorizer.Close()
-					c.isAuth = true
-					c.chanAuthReady <- c.isAuth
-					return
-				}
-			case <-c.chanAuthClose:
-				authorizer.Close()
-				c.chanAuthReady <- false
-				return
-			}
-		}
-	}()
+// SetStateHandler sets the function receiving the changes of the session state,
+// it is called from the client goroutines and must not block
+func (c *Client) SetStateHandler(handler func(AuthState)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
 
-	authorizer.TdlibParameters <- c.parameters
+	c.onState = handler
+}
+
+// Auth authorizes the client and sends the result to AuthReady. After the authorization
+// the client watches the session and reconnects when it is closed, e.g. terminated from another device.
+func (c *Client) Auth() error {
+	state, err := c.connect()
+	if err != nil && state == client.TypeAuthorizationStateLoggingOut {
+		// TDLib drops the database of a logged out session, so a new instance starts a clean login
+		c.log.Warn("telegram session was logged out, starting a new session", "error", err)
+		state, err = c.connect()
+	}
 
-	var err error
-	c.client, err = client.NewClient(authorizer)
 	if err != nil {
-		return err
+		c.setState(AUTH_STATE_CLOSED)
+		c.sendAuthReady(false)
+		return fmt.Errorf("authorize in state %s: %w", state, err)
 	}
 
+	c.sendAuthReady(true)
+
 	return nil
 }
 
@@ -208,21 +193,45 @@ func (c *Client) AuthReady() chan bool {
 	return c.chanAuthReady
 }
 
+func (c *Client) sendAuthReady(isAuth bool) {
+	select {
+	case c.chanAuthReady <- isAuth:
+	default:
+	}
+}
+
+// LogOut terminates the session, the client reconnects and asks the input handler for a new login
 func (c *Client) LogOut() error {
-	if c.client != nil {
-		_, err := c.client.LogOut()
-		if err != nil {
-			return err
-		}
+	td, err := c.tdClient()
+	if err != nil {
+		return err
 	}
 
-	c.isAuth = false
+	_, err = td.LogOut()
+	if err != nil {
+		return err
+	}
+
+	c.isAuth.Store(false)
 
 	return nil
 }
 
+func (c *Client) tdClient() (*client.Client, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if c.client == nil {
+		return nil, ErrNotAuthorized
+	}
+
+	return c.client, nil
+}
+
 func (c *Client) GetMe() (*client.User, error) {
-	return c.client.GetMe()
+	return request(c, "getMe", 0, true, func(td *client.Client) (*client.User, error) {
+		return td.GetMe()
+	})
 }
 
 func (c *Client) GetChats(chatList client.ChatList, limit int32) (*client.Chats, error) {
@@ -230,72 +239,73 @@ func (c *Client) GetChats(chatList client.ChatList, limit int32) (*client.Chats,
 		chatList = &client.ChatListMain{}
 	}
 
-	return c.client.GetChats(&client.GetChatsRequest{
-		ChatList: chatList,
-		Limit:    limit,
+	return request(c, "getChats", 0, true, func(td *client.Client) (*client.Chats, error) {
+		return td.GetChats(&client.GetChatsRequest{
+			ChatList: chatList,
+			Limit:    limit,
+		})
 	})
 }
 
 func (c *Client) GetChat(id int64) (*client.Chat, error) {
-	return c.client.GetChat(&client.GetChatRequest{ChatId: id})
+	return request(c, "getChat", id, true, func(td *client.Client) (*client.Chat, error) {
+		return td.GetChat(&client.GetChatRequest{ChatId: id})
+	})
 }
 
 func (c *Client) SearchChats(query string, limit int32) (*client.Chats, error) {
-	return c.client.SearchChats(&client.SearchChatsRequest{
-		Query: query,
-		Limit: limit,
+	return request(c, "searchChats", 0, true, func(td *client.Client) (*client.Chats, error) {
+		return td.SearchChats(&client.SearchChatsRequest{
+			Query: query,
+			Limit: limit,
+		})
 	})
 }
 
 func (c *Client) SearchPublicChat(username string) (*client.Chat, error) {
-	return c.client.SearchPublicChat(&client.SearchPublicChatRequest{
-		Username: username,
+	return request(c, "searchPublicChat", 0, true, func(td *client.Client) (*client.Chat, error) {
+		return td.SearchPublicChat(&client.SearchPublicChatRequest{
+			Username: username,
+		})
 	})
 }
 
 func (c *Client) SendMessage(chatID int64, message client.InputMessageContent) (*client.Message, error) {
-	return c.client.SendMessage(&client.SendMessageRequest{
-		ChatId:              chatID,
-		InputMessageContent: message,
+	return request(c, "sendMessage", chatID, false, func(td *client.Client) (*client.Message, error) {
+		return td.SendMessage(&client.SendMessageRequest{
+			ChatId:              chatID,
+			InputMessageContent: message,
+		})
 	})
 }
 
 func (c *Client) SendMessageText(chatID int64, message string) (*client.Message, error) {
-	return c.client.SendMessage(&client.SendMessageRequest{
-		ChatId: chatID,
-		InputMessageContent: &client.InputMessageText{
-			Text: &client.FormattedText{
-				Text: message,
-			},
+	return c.SendMessage(chatID, &client.InputMessageText{
+		Text: &client.FormattedText{
+			Text: message,
 		},
 	})
 }
 
 func (c *Client) GetChatMessages(id int64, limit int32) (*client.Messages, error) {
-	return c.client.GetChatHistory(&client.GetChatHistoryRequest{
-		ChatId: id,
-		Limit:  limit,
+	return request(c, "getChatHistory", id, true, func(td *client.Client) (*client.Messages, error) {
+		return td.GetChatHistory(&client.GetChatHistoryRequest{
+			ChatId: id,
+			Limit:  limit,
+		})
 	})
 }
 
+// GetChatMessagesText returns the texts of the last messages, empty strings for the messages without a text
 func (c *Client) GetChatMessagesText(id int64, limit int32) ([]string, error) {
-	messages, err := c.GetChatMessages(id, limit)
+	messages, err := c.GetMessages(id, 0, limit)
 	if err != nil {
 		return nil, err
 	}
 
-	textMessages := make([]string, len(messages.Messages))
-
-	for i := 0; i < len(messages.Messages); i++ {
-		contentType := messages.Messages[i].Content.MessageContentType()
-
-		if contentType == client.TypeMessageText {
-			text := messages.Messages[i].Content.(*client.MessageText).Text.Text
-
-			if len(text) != 0 {
-				textMessages[i] = text
-			}
-		}
+	textMessages := make([]string, len(messages))
+	for i, message := range messages {
+		textMessages[i] = message.Text
 	}
 
 	return textMessages, nil
@@ -313,18 +323,30 @@ func (c *Client) Version() (string, error) {
 }
 
 func (c *Client) IsAuth() bool {
-	return c.isAuth
+	return c.isAuth.Load()
 }
 
-func (c *Client) Close() {
-	close(c.chanAuthClose)
+// State returns the last reported session state
+func (c *Client) State() AuthState {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.state
+}
 
-	if c.client != nil {
-		_, err := c.client.Close()
-		if err != nil {
-			log.Println(err)
+// Close closes the session without reconnecting
+func (c *Client) Close() {
+	c.closeOnce.Do(func() {
+		close(c.chanAuthClose)
+
+		td, err := c.tdClient()
+		if err == nil {
+			_, err = td.Close()
+			if err != nil {
+				c.log.Warn("failed to close TDLib client", "error", err)
+			}
 		}
-	}
 
-	c.isAuth = false
+		c.isAuth.Store(false)
+	})
 }
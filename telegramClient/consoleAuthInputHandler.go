@@ -1,6 +1,9 @@
 package telegramClient
 
-import "fmt"
+import (
+	"fmt"
+	"wb-assistance-logistic/logger"
+)
 
 type ConsoleAuthInputHandler struct{}
 
@@ -24,3 +27,7 @@ func (a *ConsoleAuthInputHandler) InputPassword() (string, error) {
 	_, err := fmt.Scanln(&password)
 	return password, err
 }
+
+func (a *ConsoleAuthInputHandler) NotifyAuthRequired(state string) {
+	logger.Warn("telegram login is required, enter the requested value in the console", "state", state)
+}
@@ -0,0 +1,65 @@
+package telegramClient
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/zelenin/go-tdlib/client"
+)
+
+// Priority of the document downloads, the highest one since a tick waits for them
+const DOWNLOAD_PRIORITY = 32
+
+// Document is a file attached to a message
+type Document struct {
+	MessageID int64
+	FileID    int32
+	FileName  string
+	MimeType  string
+	Size      int64
+}
+
+// MessageDocument returns the document attached to the message, nil for other contents
+func MessageDocument(message *client.Message) *Document {
+	if message == nil || message.Content == nil {
+		return nil
+	}
+
+	content, ok := message.Content.(*client.MessageDocument)
+	if !ok || content.Document == nil || content.Document.Document == nil {
+		return nil
+	}
+
+	return &Document{
+		MessageID: message.Id,
+		FileID:    content.Document.Document.Id,
+		FileName:  content.Document.FileName,
+		MimeType:  content.Document.MimeType,
+		Size:      content.Document.Document.Size,
+	}
+}
+
+// DownloadDocument downloads the document into the files directory of the client and returns the local path,
+// a document downloaded before is not downloaded again
+func (c *Client) DownloadDocument(document *Document) (string, error) {
+	if document == nil {
+		return "", errors.New("document can not be nil")
+	}
+
+	file, err := request(c, "downloadFile", 0, true, func(td *client.Client) (*client.File, error) {
+		return td.DownloadFile(&client.DownloadFileRequest{
+			FileId:      document.FileID,
+			Priority:    DOWNLOAD_PRIORITY,
+			Synchronous: true,
+		})
+	})
+	if err != nil {
+		return "", fmt.Errorf("download %q: %w", document.FileName, err)
+	}
+
+	if file.Local == nil || !file.Local.IsDownloadingCompleted {
+		return "", fmt.Errorf("download %q: not completed", document.FileName)
+	}
+
+	return file.Local.Path, nil
+}
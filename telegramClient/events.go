@@ -0,0 +1,101 @@
+package telegramClient
+
+import (
+	"time"
+
+	"github.com/zelenin/go-tdlib/client"
+)
+
+type MessageEventType string
+
+const (
+	MESSAGE_NEW     MessageEventType = "new"     // A message was received or sent
+	MESSAGE_CONTENT MessageEventType = "content" // The content of a message was changed, e.g. edited by a bot
+	MESSAGE_EDITED  MessageEventType = "edited"  // A message was edited, it follows the content change
+)
+
+// Events buffered for a subscriber, the events over it are dropped
+const EVENTS_BUFFER_SIZE = 100
+
+// MessageEvent is a change of a message in a chat
+type MessageEvent struct {
+	Type       MessageEventType
+	ChatID     int64
+	MessageID  int64
+	IsOutgoing bool      // Known only for the new messages
+	Message    *Message  // The new message, nil for the other events
+	EditDate   time.Time // The edited events only
+	Time       time.Time // The time the event was received
+}
+
+type subscriber struct {
+	chatID int64
+	events chan *MessageEvent
+}
+
+// SubscribeMessages returns the events of the chat until the returned function is called.
+// A subscriber which does not keep up with the events loses the newest ones.
+func (c *Client) SubscribeMessages(chatID int64) (<-chan *MessageEvent, func()) {
+	sub := &subscriber{
+		chatID: chatID,
+		events: make(chan *MessageEvent, EVENTS_BUFFER_SIZE),
+	}
+
+	c.mu.Lock()
+	c.subscribers[sub] = struct{}{}
+	c.mu.Unlock()
+
+	unsubscribe := func() {
+		c.mu.Lock()
+		delete(c.subscribers, sub)
+		c.mu.Unlock()
+	}
+
+	return sub.events, unsubscribe
+}
+
+// dispatchMessageEvent passes a message update to the subscribers of its chat, other updates are ignored
+func (c *Client) dispatchMessageEvent(update client.Type) {
+	event := &MessageEvent{Time: time.Now()}
+
+	switch update := update.(type) {
+	case *client.UpdateNewMessage:
+		if update.Message == nil {
+			return
+		}
+		event.Type = MESSAGE_NEW
+		event.ChatID = update.Message.ChatId
+		event.MessageID = update.Message.Id
+		event.IsOutgoing = update.Message.IsOutgoing
+		event.Message = newMessage(update.Message)
+
+	case *client.UpdateMessageContent:
+		event.Type = MESSAGE_CONTENT
+		event.ChatID = update.ChatId
+		event.MessageID = update.MessageId
+
+	case *client.UpdateMessageEdited:
+		event.Type = MESSAGE_EDITED
+		event.ChatID = update.ChatId
+		event.MessageID = update.MessageId
+		event.EditDate = time.Unix(int64(update.EditDate), 0)
+
+	default:
+		return
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	for sub := range c.subscribers {
+		if sub.chatID != event.ChatID {
+			continue
+		}
+
+		select {
+		case sub.events <- event:
+		default:
+			c.log.Debug("message event dropped, the subscriber is full", "chat_id", event.ChatID, "type", event.Type)
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package telegramClient
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+	"wb-assistance-logistic/logger"
+)
+
+const (
+	PHONE_NUMBER_FILE = "phone_number"
+	CODE_FILE         = "code"
+	PASSWORD_FILE     = "password"
+
+	FILE_POLL_INTERVAL = time.Second
+)
+
+// FileAuthInputHandler waits for the values dropped as files into the directory, a file is removed after reading.
+// The code file must be written after the code was requested, so a stale code is never sent.
+type FileAuthInputHandler struct {
+	directory string
+	log       *slog.Logger
+}
+
+func NewFileAuthInputHandler(directory string) (*FileAuthInputHandler, error) {
+	if directory == "" {
+		return nil, errors.New("directory can not be empty")
+	}
+
+	err := os.MkdirAll(directory, 0o700)
+	if err != nil {
+		return nil, fmt.Errorf("create drop directory: %w", err)
+	}
+
+	return &FileAuthInputHandler{
+		directory: directory,
+		log:       logger.With("component", "telegram_auth"),
+	}, nil
+}
+
+func (h *FileAuthInputHandler) InputPhoneNumber() (string, error) {
+	return h.wait(PHONE_NUMBER_FILE, time.Time{})
+}
+
+func (h *FileAuthInputHandler) InputCode() (string, error) {
+	return h.wait(CODE_FILE, time.Now())
+}
+
+func (h *FileAuthInputHandler) InputPassword() (string, error) {
+	return h.wait(PASSWORD_FILE, time.Time{})
+}
+
+func (h *FileAuthInputHandler) wait(name string, since time.Time) (string, error) {
+	path := filepath.Join(h.directory, name)
+	h.log.Warn("waiting for the telegram login file", "path", path)
+
+	for {
+		info, err := os.Stat(path)
+		if err == nil && !info.ModTime().Before(since) {
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return "", fmt.Errorf("read %s: %w", name, err)
+			}
+
+			err = os.Remove(path)
+			if err != nil {
+				h.log.Warn("failed to remove the telegram login file", "path", path, "error", err)
+			}
+
+			value := strings.TrimSpace(string(data))
+			if value != "" {
+				return value, nil
+			}
+		} else if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return "", fmt.Errorf("stat %s: %w", name, err)
+		}
+
+		time.Sleep(FILE_POLL_INTERVAL)
+	}
+}
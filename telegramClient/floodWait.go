@@ -0,0 +1,53 @@
+package telegramClient
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/zelenin/go-tdlib/client"
+)
+
+// TDLib reports a flood wait as "Too Many Requests: retry after N", the raw MTProto error is FLOOD_WAIT_N
+var floodWaitPattern = regexp.MustCompile(`(?:retry after |FLOOD_WAIT_)(\d+)`)
+
+// FloodWaitError is returned when Telegram limits the requests, no request should be sent before the wait is over
+type FloodWaitError struct {
+	Method string
+	Wait   time.Duration
+	Err    error
+}
+
+func (e *FloodWaitError) Error() string {
+	return fmt.Sprintf("telegram flood wait on %s, retry after %s", e.Method, e.Wait)
+}
+
+func (e *FloodWaitError) Unwrap() error {
+	return e.Err
+}
+
+// RetryAfter returns the time to wait before the next request
+func (e *FloodWaitError) RetryAfter() time.Duration {
+	return e.Wait
+}
+
+// asFloodWait converts a 429 response to a FloodWaitError, other errors are returned as is
+func asFloodWait(method string, err error) error {
+	var responseErr client.ResponseError
+	if !errors.As(err, &responseErr) || responseErr.Err == nil || responseErr.Err.Code != http.StatusTooManyRequests {
+		return err
+	}
+
+	wait := time.Second
+	if match := floodWaitPattern.FindStringSubmatch(responseErr.Err.Message); match != nil {
+		seconds, parseErr := strconv.Atoi(match[1])
+		if parseErr == nil {
+			wait = time.Duration(seconds) * time.Second
+		}
+	}
+
+	return &FloodWaitError{Method: method, Wait: wait, Err: err}
+}
@@ -0,0 +1,113 @@
+package telegramClient
+
+import (
+	"context"
+	"errors"
+	"html/template"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+	"wb-assistance-logistic/httpServer"
+	"wb-assistance-logistic/logger"
+)
+
+const HTTP_INPUT_SHUTDOWN_TIMEOUT = 5 * time.Second
+
+var inputPage = template.Must(template.New("input").Parse(`<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><title>Telegram login</title></head>
+<body>
+{{if .Done}}<p>{{.Label}} accepted.</p>{{else}}
+<form method="post">
+<label>{{.Label}}: <input name="value" type="{{.Type}}" autocomplete="off" autofocus required></label>
+<button type="submit">Submit</button>
+</form>{{end}}
+</body>
+</html>
+`))
+
+// HTTPAuthInputHandler serves a page with a form for the requested value while the value is awaited.
+// The page has no authentication, so the address should be a local one.
+type HTTPAuthInputHandler struct {
+	address string
+	log     *slog.Logger
+}
+
+func NewHTTPAuthInputHandler(address string) (*HTTPAuthInputHandler, error) {
+	if address == "" {
+		return nil, errors.New("address can not be empty")
+	}
+
+	return &HTTPAuthInputHandler{
+		address: address,
+		log:     logger.With("component", "telegram_auth"),
+	}, nil
+}
+
+func (h *HTTPAuthInputHandler) InputPhoneNumber() (string, error) {
+	return h.ask("Phone number", "tel")
+}
+
+func (h *HTTPAuthInputHandler) InputCode() (string, error) {
+	return h.ask("Login code", "text")
+}
+
+func (h *HTTPAuthInputHandler) InputPassword() (string, error) {
+	return h.ask("Password", "password")
+}
+
+func (h *HTTPAuthInputHandler) ask(label, inputType string) (string, error) {
+	values := make(chan string, 1)
+
+	server := httpServer.NewServer(h.address)
+	server.HandleFunc("GET /{$}", func(w http.ResponseWriter, r *http.Request) {
+		h.render(w, label, inputType, false)
+	})
+	server.HandleFunc("POST /{$}", func(w http.ResponseWriter, r *http.Request) {
+		value := strings.TrimSpace(r.FormValue("value"))
+		if value == "" {
+			http.Redirect(w, r, "/", http.StatusSeeOther)
+			return
+		}
+
+		select {
+		case values <- value:
+			h.render(w, label, inputType, true)
+		default:
+			http.Error(w, "the value was already submitted", http.StatusConflict)
+		}
+	})
+
+	err := server.Start()
+	if err != nil {
+		return "", err
+	}
+
+	h.log.Warn("waiting for the telegram login input", "input", label, "url", "http://"+h.address+"/")
+	value := <-values
+
+	ctx, cancel := context.WithTimeout(context.Background(), HTTP_INPUT_SHUTDOWN_TIMEOUT)
+	defer cancel()
+
+	err = server.Stop(ctx)
+	if err != nil {
+		h.log.Warn("failed to stop the telegram login page", "error", err)
+	}
+
+	return value, nil
+}
+
+func (h *HTTPAuthInputHandler) render(w http.ResponseWriter, label, inputType string, done bool) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+
+	err := inputPage.Execute(w, struct {
+		Label string
+		Type  string
+		Done  bool
+	}{label, inputType, done})
+	if err != nil {
+		h.log.Warn("failed to render the telegram login page", "error", err)
+	}
+}
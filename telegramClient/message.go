@@ -0,0 +1,250 @@
+package telegramClient
+
+import (
+	"errors"
+	"net/http"
+	"time"
+	"unicode/utf16"
+
+	"github.com/zelenin/go-tdlib/client"
+)
+
+// The largest page of the chat history TDLib returns
+const HISTORY_PAGE_SIZE = 100
+
+// Entity is a formatting entity of a text, Offset and Length are counted in UTF-16 code units as in TDLib
+type Entity struct {
+	Type     string // TDLib entity type, e.g. client.TypeTextEntityTypePre
+	Offset   int32
+	Length   int32
+	URL      string // Text URL entities
+	Language string // Pre code entities
+}
+
+// Message is a chat message with the fields the parser needs, the TDLib message is kept in Raw
+type Message struct {
+	ID           int64
+	ChatID       int64
+	SenderUserID int64 // Zero when the message is sent on behalf of a chat
+	SenderChatID int64
+	IsOutgoing   bool
+	Date         time.Time
+	EditDate     time.Time // Zero when the message was never edited
+	ReplyToID    int64     // Zero when the message is not a reply or replies to another chat
+	ContentType  string    // TDLib content type, e.g. client.TypeMessageText
+	Text         string    // Text of a text message
+	Caption      string    // Caption of a media message
+	Entities     []*Entity // Entities of the text or the caption
+	Document     *Document // Attached document, nil for other contents
+	Raw          *client.Message
+}
+
+func newMessage(message *client.Message) *Message {
+	m := &Message{
+		ID:         message.Id,
+		ChatID:     message.ChatId,
+		IsOutgoing: message.IsOutgoing,
+		Date:       time.Unix(int64(message.Date), 0),
+		Document:   MessageDocument(message),
+		Raw:        message,
+	}
+
+	if message.EditDate != 0 {
+		m.EditDate = time.Unix(int64(message.EditDate), 0)
+	}
+
+	switch sender := message.SenderId.(type) {
+	case *client.MessageSenderUser:
+		m.SenderUserID = sender.UserId
+	case *client.MessageSenderChat:
+		m.SenderChatID = sender.ChatId
+	}
+
+	if reply, ok := message.ReplyTo.(*client.MessageReplyToMessage); ok && (reply.ChatId == 0 || reply.ChatId == message.ChatId) {
+		m.ReplyToID = reply.MessageId
+	}
+
+	if message.Content == nil {
+		return m
+	}
+	m.ContentType = message.Content.MessageContentType()
+
+	var formatted *client.FormattedText
+	switch content := message.Content.(type) {
+	case *client.MessageText:
+		formatted = content.Text
+		m.Text = formattedText(content.Text)
+	case *client.MessageDocument:
+		formatted = content.Caption
+	case *client.MessagePhoto:
+		formatted = content.Caption
+	case *client.MessageVideo:
+		formatted = content.Caption
+	case *client.MessageAudio:
+		formatted = content.Caption
+	case *client.MessageAnimation:
+		formatted = content.Caption
+	case *client.MessageVoiceNote:
+		formatted = content.Caption
+	}
+
+	if formatted == nil {
+		return m
+	}
+
+	if m.ContentType != client.TypeMessageText {
+		m.Caption = formatted.Text
+	}
+
+	for _, entity := range formatted.Entities {
+		e := &Entity{
+			Type:   entity.Type.TextEntityTypeType(),
+			Offset: entity.Offset,
+			Length: entity.Length,
+		}
+
+		switch entityType := entity.Type.(type) {
+		case *client.TextEntityTypeTextUrl:
+			e.URL = entityType.Url
+		case *client.TextEntityTypePreCode:
+			e.Language = entityType.Language
+		}
+
+		m.Entities = append(m.Entities, e)
+	}
+
+	return m
+}
+
+func formattedText(text *client.FormattedText) string {
+	if text == nil {
+		return ""
+	}
+
+	return text.Text
+}
+
+// EntityText returns the part of the text or the caption covered by the entity
+func (m *Message) EntityText(entity *Entity) string {
+	text := m.Text
+	if text == "" {
+		text = m.Caption
+	}
+
+	units := utf16.Encode([]rune(text))
+
+	start := int(entity.Offset)
+	end := start + int(entity.Length)
+	if start < 0 || start > end || end > len(units) {
+		return ""
+	}
+
+	return string(utf16.Decode(units[start:end]))
+}
+
+// IsEdited reports whether the message was edited after sending
+func (m *Message) IsEdited() bool {
+	return !m.EditDate.IsZero()
+}
+
+// GetMessages returns up to limit messages from the message fromMessageID back in the history,
+// 0 starts from the last message. TDLib can return fewer messages than the limit.
+func (c *Client) GetMessages(chatID, fromMessageID int64, limit int32) ([]*Message, error) {
+	history, err := request(c, "getChatHistory", chatID, true, func(td *client.Client) (*client.Messages, error) {
+		return td.GetChatHistory(&client.GetChatHistoryRequest{
+			ChatId:        chatID,
+			FromMessageId: fromMessageID,
+			Limit:         limit,
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	messages := make([]*Message, 0, len(history.Messages))
+	for _, message := range history.Messages {
+		if message != nil {
+			messages = append(messages, newMessage(message))
+		}
+	}
+
+	return messages, nil
+}
+
+// IterateHistory calls fn for the messages from fromMessageID back to toMessageID, both included,
+// from the newest to the oldest. Zero fromMessageID starts from the last message, zero toMessageID
+// goes to the beginning of the chat. The iteration stops when fn returns false.
+func (c *Client) IterateHistory(chatID, fromMessageID, toMessageID int64, fn func(*Message) bool) error {
+	from := fromMessageID
+	last := int64(0)
+
+	for {
+		page, err := c.GetMessages(chatID, from, HISTORY_PAGE_SIZE)
+		if err != nil {
+			return err
+		}
+
+		found := false
+		for _, message := range page {
+			// A next page starts from the last message of the previous one
+			if last != 0 && message.ID >= last {
+				continue
+			}
+			found = true
+
+			if toMessageID != 0 && message.ID < toMessageID {
+				return nil
+			}
+
+			if !fn(message) {
+				return nil
+			}
+
+			last = message.ID
+		}
+
+		if !found {
+			return nil
+		}
+
+		from = last
+	}
+}
+
+// IterateHistoryByDate calls fn for the messages sent between the dates, both included, from the newest
+// to the oldest. A zero from goes to the beginning of the chat, a zero to starts from the last message.
+func (c *Client) IterateHistoryByDate(chatID int64, from, to time.Time, fn func(*Message) bool) error {
+	start := int64(0)
+
+	if !to.IsZero() {
+		message, err := request(c, "getChatMessageByDate", chatID, true, func(td *client.Client) (*client.Message, error) {
+			return td.GetChatMessageByDate(&client.GetChatMessageByDateRequest{
+				ChatId: chatID,
+				Date:   int32(to.Unix()),
+			})
+		})
+
+		var responseErr client.ResponseError
+		if errors.As(err, &responseErr) && responseErr.Err.Code == http.StatusNotFound {
+			// No messages were sent before the date
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		start = message.Id
+	}
+
+	return c.IterateHistory(chatID, start, 0, func(message *Message) bool {
+		if !from.IsZero() && message.Date.Before(from) {
+			return false
+		}
+
+		if !to.IsZero() && message.Date.After(to) {
+			return true
+		}
+
+		return fn(message)
+	})
+}
@@ -0,0 +1,14 @@
+package telegramClient
+
+import (
+	"time"
+	"wb-assistance-logistic/metrics"
+)
+
+func observeRequest(method string, start time.Time) {
+	metrics.TelegramRequestDuration.WithLabelValues(method).ObserveDuration(start)
+}
+
+func observeFloodWait(method string) {
+	metrics.TelegramFloodWaits.WithLabelValues(method).Inc()
+}
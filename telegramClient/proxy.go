@@ -0,0 +1,173 @@
+package telegramClient
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/zelenin/go-tdlib/client"
+)
+
+type ProxyType string
+
+const (
+	SOCKS5_PROXY  ProxyType = "socks5"
+	HTTP_PROXY    ProxyType = "http"
+	MTPROTO_PROXY ProxyType = "mtproto"
+)
+
+const DEFAULT_PROXY_CHECK_INTERVAL = time.Minute
+
+type Proxy struct {
+	Type     ProxyType
+	Server   string
+	Port     int32
+	Username string // SOCKS5 and HTTP
+	Password string // SOCKS5 and HTTP
+	HttpOnly bool   // HTTP proxy used only for HTTP requests, it can not carry MTProto
+	Secret   string // MTProto
+}
+
+func (p *Proxy) String() string {
+	return fmt.Sprintf("%s://%s:%d", p.Type, p.Server, p.Port)
+}
+
+func (p *Proxy) tdType() (client.ProxyType, error) {
+	switch p.Type {
+	case SOCKS5_PROXY:
+		return &client.ProxyTypeSocks5{Username: p.Username, Password: p.Password}, nil
+	case HTTP_PROXY:
+		return &client.ProxyTypeHttp{Username: p.Username, Password: p.Password, HttpOnly: p.HttpOnly}, nil
+	case MTPROTO_PROXY:
+		if p.Secret == "" {
+			return nil, fmt.Errorf("proxy %s: secret can not be empty", p)
+		}
+		return &client.ProxyTypeMtproto{Secret: p.Secret}, nil
+	}
+
+	return nil, fmt.Errorf("proxy %s: unknown type %q", p, p.Type)
+}
+
+// SetProxies sets the proxies the client connects through, the first one is used until it fails the check.
+// Every checkInterval the used proxy is pinged and the next working one is enabled after a failure.
+func (c *Client) SetProxies(proxies []*Proxy, checkInterval time.Duration) error {
+	for _, proxy := range proxies {
+		if proxy == nil {
+			return errors.New("proxy can not be nil")
+		}
+		if proxy.Server == "" {
+			return errors.New("proxy server can not be empty")
+		}
+		if proxy.Port <= 0 || proxy.Port > 65535 {
+			return fmt.Errorf("proxy %s: invalid port", proxy)
+		}
+		if _, err := proxy.tdType(); err != nil {
+			return err
+		}
+	}
+
+	if checkInterval <= 0 {
+		checkInterval = DEFAULT_PROXY_CHECK_INTERVAL
+	}
+
+	c.proxies = proxies
+	c.proxyCheckInterval = checkInterval
+
+	return nil
+}
+
+// applyProxies replaces the proxies saved in the TDLib database with the configured ones and enables the first one
+func (c *Client) applyProxies(td *client.Client) ([]int32, error) {
+	if len(c.proxies) == 0 {
+		return nil, nil
+	}
+
+	saved, err := td.GetProxies()
+	if err != nil {
+		return nil, fmt.Errorf("get proxies: %w", err)
+	}
+
+	for _, proxy := range saved.Proxies {
+		_, err = td.RemoveProxy(&client.RemoveProxyRequest{ProxyId: proxy.Id})
+		if err != nil {
+			return nil, fmt.Errorf("remove proxy %s:%d: %w", proxy.Server, proxy.Port, err)
+		}
+	}
+
+	ids := make([]int32, 0, len(c.proxies))
+	for i, proxy := range c.proxies {
+		proxyType, _ := proxy.tdType()
+
+		added, err := td.AddProxy(&client.AddProxyRequest{
+			Server: proxy.Server,
+			Port:   proxy.Port,
+			Enable: i == 0,
+			Type:   proxyType,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("add proxy %s: %w", proxy, err)
+		}
+
+		ids = append(ids, added.Id)
+	}
+
+	c.log.Info("telegram proxy enabled", "proxy", c.proxies[0].String(), "proxies", len(ids))
+
+	return ids, nil
+}
+
+// monitorProxies switches to the next working proxy when the used one fails the ping, until done is closed
+func (c *Client) monitorProxies(td *client.Client, ids []int32, done <-chan struct{}) {
+	if len(ids) < 2 {
+		return
+	}
+
+	ticker := time.NewTicker(c.proxyCheckInterval)
+	defer ticker.Stop()
+
+	current := 0
+	for {
+		select {
+		case <-ticker.C:
+		case <-done:
+			return
+		}
+
+		err := c.pingProxy(td, ids[current])
+		if err == nil {
+			continue
+		}
+
+		c.log.Warn("telegram proxy check failed", "proxy", c.proxies[current].String(), "error", err)
+
+		switched := false
+		for i := 1; i < len(ids); i++ {
+			next := (current + i) % len(ids)
+			if c.pingProxy(td, ids[next]) != nil {
+				continue
+			}
+
+			_, err = td.EnableProxy(&client.EnableProxyRequest{ProxyId: ids[next]})
+			if err != nil {
+				c.log.Warn("failed to enable telegram proxy", "proxy", c.proxies[next].String(), "error", err)
+				continue
+			}
+
+			c.log.Warn("switched telegram proxy", "from", c.proxies[current].String(), "to", c.proxies[next].String())
+			current = next
+			switched = true
+			break
+		}
+
+		if !switched {
+			c.log.Error("all telegram proxies failed the check", "proxies", len(ids))
+		}
+	}
+}
+
+func (c *Client) pingProxy(td *client.Client, id int32) error {
+	defer observeRequest("pingProxy", time.Now())
+
+	_, err := td.PingProxy(&client.PingProxyRequest{ProxyId: id})
+	return err
+}
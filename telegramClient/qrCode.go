@@ -0,0 +1,77 @@
+package telegramClient
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/skip2/go-qrcode"
+)
+
+type LoginMode string
+
+const (
+	PHONE_LOGIN LoginMode = "phone" // The phone number and the code from the input handler
+	QR_LOGIN    LoginMode = "qr"    // A QR code scanned by a device where the account is logged in
+)
+
+const QR_CODE_PNG_SIZE = 512
+
+// SetLoginMode chooses how a new session is authorized. The QR code is printed to the console
+// and also written as a PNG file when the path is not empty.
+func (c *Client) SetLoginMode(mode LoginMode, qrCodeFile string) error {
+	switch mode {
+	case "", PHONE_LOGIN:
+		mode = PHONE_LOGIN
+	case QR_LOGIN:
+	default:
+		return fmt.Errorf("unknown login mode %q", mode)
+	}
+
+	c.loginMode = mode
+	c.qrCodeFile = qrCodeFile
+
+	return nil
+}
+
+func (c *Client) showQRCode(link string) error {
+	code, err := qrcode.New(link, qrcode.Medium)
+	if err != nil {
+		return fmt.Errorf("create qr code: %w", err)
+	}
+
+	fmt.Println("Scan the QR code in Telegram: Settings > Devices > Link Desktop Device")
+	fmt.Println(code.ToSmallString(false))
+
+	if c.qrCodeFile == "" {
+		c.log.Warn("telegram login is required, scan the QR code printed to the console")
+		return nil
+	}
+
+	err = os.MkdirAll(filepath.Dir(c.qrCodeFile), 0o700)
+	if err != nil {
+		return fmt.Errorf("create qr code directory: %w", err)
+	}
+
+	err = code.WriteFile(QR_CODE_PNG_SIZE, c.qrCodeFile)
+	if err != nil {
+		return fmt.Errorf("write qr code file: %w", err)
+	}
+
+	c.log.Warn("telegram login is required, scan the QR code", "path", c.qrCodeFile)
+
+	return nil
+}
+
+// removeQRCodeFile drops the code of a finished login, it can not be used again anyway
+func (c *Client) removeQRCodeFile() {
+	if c.qrCodeFile == "" {
+		return
+	}
+
+	err := os.Remove(c.qrCodeFile)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		c.log.Warn("failed to remove qr code file", "path", c.qrCodeFile, "error", err)
+	}
+}
@@ -0,0 +1,149 @@
+package telegramClient
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/zelenin/go-tdlib/client"
+)
+
+const (
+	DEFAULT_READ_RETRIES   = 2
+	DEFAULT_MAX_RETRY_WAIT = 30 * time.Second
+)
+
+// RateLimit configures the request pacing of the client
+type RateLimit struct {
+	ChatInterval time.Duration // Minimal interval between the requests to the same chat, zero disables the limiter
+	ReadRetries  int           // Flood waits retried by the read requests
+	MaxRetryWait time.Duration // Longer flood waits are returned to the caller without waiting
+}
+
+var DefaultRateLimit = RateLimit{
+	ReadRetries:  DEFAULT_READ_RETRIES,
+	MaxRetryWait: DEFAULT_MAX_RETRY_WAIT,
+}
+
+// limiter spaces the requests to every chat and holds all the requests during a flood wait
+type limiter struct {
+	mu         sync.Mutex
+	rate       RateLimit
+	next       map[int64]time.Time
+	floodUntil time.Time
+}
+
+func newLimiter(rate RateLimit) *limiter {
+	return &limiter{
+		rate: rate,
+		next: map[int64]time.Time{},
+	}
+}
+
+// waitChat blocks until a request to the chat is allowed, zero chat id is not limited
+func (l *limiter) waitChat(chatID int64) {
+	l.mu.Lock()
+	if l.rate.ChatInterval <= 0 || chatID == 0 {
+		l.mu.Unlock()
+		return
+	}
+
+	now := time.Now()
+	at := l.next[chatID]
+	if at.Before(now) {
+		at = now
+	}
+	l.next[chatID] = at.Add(l.rate.ChatInterval)
+	l.mu.Unlock()
+
+	time.Sleep(time.Until(at))
+}
+
+// floodWait returns the time left of the current flood wait
+func (l *limiter) floodWait() time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return time.Until(l.floodUntil)
+}
+
+func (l *limiter) setFloodWait(wait time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if until := time.Now().Add(wait); until.After(l.floodUntil) {
+		l.floodUntil = until
+	}
+}
+
+func (l *limiter) rateLimit() RateLimit {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.rate
+}
+
+func (l *limiter) setRateLimit(rate RateLimit) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.rate = rate
+}
+
+// SetRateLimit replaces the request pacing, see DefaultRateLimit
+func (c *Client) SetRateLimit(rate RateLimit) error {
+	if rate.ChatInterval < 0 || rate.ReadRetries < 0 || rate.MaxRetryWait < 0 {
+		return errors.New("rate limit values can not be negative")
+	}
+
+	c.limiter.setRateLimit(rate)
+	return nil
+}
+
+// request sends a TDLib request through the limiter. During a flood wait nothing is sent, since a request
+// extends the ban: idempotent requests wait and retry when the wait is short, the others return a FloodWaitError.
+func request[T any](c *Client, method string, chatID int64, idempotent bool, send func(td *client.Client) (T, error)) (T, error) {
+	var zero T
+	retries := 0
+
+	for {
+		if wait := c.limiter.floodWait(); wait > 0 {
+			rate := c.limiter.rateLimit()
+			if !idempotent || retries >= rate.ReadRetries || wait > rate.MaxRetryWait {
+				return zero, &FloodWaitError{Method: method, Wait: wait}
+			}
+
+			retries++
+			c.log.Warn("waiting for the telegram flood wait", "method", method, "wait", wait, "retry", retries)
+			time.Sleep(wait)
+		}
+
+		c.limiter.waitChat(chatID)
+
+		td, err := c.tdClient()
+		if err != nil {
+			return zero, err
+		}
+
+		start := time.Now()
+		result, err := send(td)
+		observeRequest(method, start)
+
+		if err == nil {
+			return result, nil
+		}
+
+		var floodErr *FloodWaitError
+		if !errors.As(asFloodWait(method, err), &floodErr) {
+			return zero, err
+		}
+
+		observeFloodWait(method)
+		c.limiter.setFloodWait(floodErr.Wait)
+		c.log.Warn("telegram flood wait", "method", method, "wait", floodErr.Wait)
+
+		if !idempotent {
+			return zero, floodErr
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package telegramClient
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+const (
+	ENV_SECRET_PREFIX  = "env:"
+	FILE_SECRET_PREFIX = "file:"
+)
+
+// SecretAuthInputHandler reads the phone number and the password from env variables or secret files,
+// e.g. "env:TG_PHONE_NUMBER" or "file:/run/secrets/tg_password". The code and the values without
+// a reference are asked from the next handler.
+type SecretAuthInputHandler struct {
+	PhoneNumber string
+	Password    string
+	Next        AuthInputHandler
+}
+
+func (h *SecretAuthInputHandler) InputPhoneNumber() (string, error) {
+	if h.PhoneNumber == "" {
+		return h.Next.InputPhoneNumber()
+	}
+
+	return readSecret(h.PhoneNumber)
+}
+
+func (h *SecretAuthInputHandler) InputCode() (string, error) {
+	return h.Next.InputCode()
+}
+
+func (h *SecretAuthInputHandler) InputPassword() (string, error) {
+	if h.Password == "" {
+		return h.Next.InputPassword()
+	}
+
+	return readSecret(h.Password)
+}
+
+func (h *SecretAuthInputHandler) NotifyAuthRequired(state string) {
+	if notifier, ok := h.Next.(AuthRequiredNotifier); ok {
+		notifier.NotifyAuthRequired(state)
+	}
+}
+
+func readSecret(reference string) (string, error) {
+	var value string
+
+	switch {
+	case strings.HasPrefix(reference, ENV_SECRET_PREFIX):
+		name := strings.TrimPrefix(reference, ENV_SECRET_PREFIX)
+		value = os.Getenv(name)
+		if value == "" {
+			return "", fmt.Errorf("env variable %s is not set", name)
+		}
+
+	case strings.HasPrefix(reference, FILE_SECRET_PREFIX):
+		path := strings.TrimPrefix(reference, FILE_SECRET_PREFIX)
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return "", fmt.Errorf("read secret file: %w", err)
+		}
+		value = strings.TrimSpace(string(data))
+		if value == "" {
+			return "", fmt.Errorf("secret file %s is empty", path)
+		}
+
+	default:
+		return "", fmt.Errorf("invalid secret reference %q, expected env:NAME or file:PATH", reference)
+	}
+
+	return value, nil
+}
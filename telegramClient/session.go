@@ -0,0 +1,209 @@
+package telegramClient
+
+import (
+	"errors"
+	"time"
+
+	"github.com/zelenin/go-tdlib/client"
+)
+
+// AuthState is the state of the TDLib session reported to the state handler
+type AuthState string
+
+const (
+	AUTH_STATE_READY        AuthState = "ready"        // The session is authorized
+	AUTH_STATE_WAIT_INPUT   AuthState = "wait_input"   // The phone number, the code or the password is required
+	AUTH_STATE_LOGGING_OUT  AuthState = "logging_out"  // The session was terminated, e.g. from another device
+	AUTH_STATE_CLOSED       AuthState = "closed"       // The TDLib instance is closed
+	AUTH_STATE_RECONNECTING AuthState = "reconnecting" // A new TDLib instance is being created
+)
+
+const (
+	RECONNECT_DELAY     = 5 * time.Second
+	RECONNECT_MAX_DELAY = 5 * time.Minute
+)
+
+var ErrNotAuthorized = errors.New("telegram client is not authorized")
+
+var errClientClosed = errors.New("client is closed")
+
+// AuthRequiredNotifier is implemented by the input handlers which should know that an interactive login is required,
+// e.g. to call an operator who is not watching the console. The state is the TDLib authorization state type.
+type AuthRequiredNotifier interface {
+	NotifyAuthRequired(state string)
+}
+
+// connect creates a TDLib instance and passes it through the authorization, it returns the last seen state type.
+// A logged out session returns the logging out state, TDLib passes the closing state after it.
+func (c *Client) connect() (string, error) {
+	authorizer := &authorizer{client: c, proxyDone: make(chan struct{})}
+
+	tdClient, err := client.NewClient(authorizer)
+	if err != nil {
+		close(authorizer.proxyDone)
+		if authorizer.loggedOut {
+			return client.TypeAuthorizationStateLoggingOut, err
+		}
+		return authorizer.lastState, err
+	}
+
+	c.mu.Lock()
+	c.client = tdClient
+	c.mu.Unlock()
+
+	c.setState(AUTH_STATE_READY)
+	c.removeQRCodeFile()
+
+	c.user, err = c.GetMe()
+	if err != nil {
+		c.log.Warn("failed to get the authorized user", "error", err)
+	}
+
+	go c.watch(tdClient, authorizer.proxyDone)
+
+	return client.TypeAuthorizationStateReady, nil
+}
+
+func (c *Client) requireInput(state string) {
+	c.setState(AUTH_STATE_WAIT_INPUT)
+
+	if notifier, ok := c.inputHandler.(AuthRequiredNotifier); ok {
+		notifier.NotifyAuthRequired(state)
+	}
+}
+
+type inputResult struct {
+	value string
+	err   error
+}
+
+// readInput asks the input handler until it returns a value, TDLib waits for the value anyway.
+// It stops when the client is closed, a blocked handler call is left to return on its own.
+func (c *Client) readInput(name string, read func() (string, error)) (string, error) {
+	for {
+		result := make(chan inputResult, 1)
+		go func() {
+			value, err := read()
+			result <- inputResult{value: value, err: err}
+		}()
+
+		select {
+		case r := <-result:
+			if r.err == nil {
+				return r.value, nil
+			}
+			c.log.Warn("failed to read "+name, "error", r.err)
+		case <-c.chanAuthClose:
+			return "", errClientClosed
+		}
+
+		select {
+		case <-time.After(time.Second):
+		case <-c.chanAuthClose:
+			return "", errClientClosed
+		}
+	}
+}
+
+// watch follows the authorization state of an authorized instance and reconnects after it is closed,
+// done is closed when the instance is not used anymore. The message updates are passed to the subscribers.
+func (c *Client) watch(tdClient *client.Client, done chan struct{}) {
+	listener := tdClient.GetListener()
+	defer listener.Close()
+
+	for {
+		select {
+		case update := <-listener.Updates:
+			stateUpdate, ok := update.(*client.UpdateAuthorizationState)
+			if !ok {
+				c.dispatchMessageEvent(update)
+				continue
+			}
+
+			switch stateUpdate.AuthorizationState.AuthorizationStateType() {
+			case client.TypeAuthorizationStateReady:
+				c.setState(AUTH_STATE_READY)
+
+			case client.TypeAuthorizationStateLoggingOut:
+				c.log.Warn("telegram session is logging out")
+				c.setState(AUTH_STATE_LOGGING_OUT)
+
+			case client.TypeAuthorizationStateClosed:
+				c.log.Warn("telegram session is closed")
+				c.setState(AUTH_STATE_CLOSED)
+				close(done)
+				c.reconnect()
+				return
+
+			case client.TypeAuthorizationStateWaitPhoneNumber,
+				client.TypeAuthorizationStateWaitCode,
+				client.TypeAuthorizationStateWaitPassword:
+				c.setState(AUTH_STATE_WAIT_INPUT)
+			}
+		case <-c.chanAuthClose:
+			close(done)
+			return
+		}
+	}
+}
+
+// reconnect creates new TDLib instances until one is authorized or the client is closed.
+// A logged out session starts from the phone number, so the input handler is asked again.
+func (c *Client) reconnect() {
+	c.mu.Lock()
+	c.client = nil
+	c.mu.Unlock()
+
+	delay := RECONNECT_DELAY
+
+	for {
+		select {
+		case <-c.chanAuthClose:
+			return
+		default:
+		}
+
+		c.log.Info("reconnecting telegram session")
+		c.setState(AUTH_STATE_RECONNECTING)
+
+		state, err := c.connect()
+		if err == nil {
+			c.log.Info("telegram session restored", "user_id", c.userID())
+			return
+		}
+
+		c.log.Warn("telegram reconnect failed", "state", state, "error", err, "retry_in", delay)
+		c.setState(AUTH_STATE_CLOSED)
+
+		select {
+		case <-time.After(delay):
+		case <-c.chanAuthClose:
+			return
+		}
+
+		delay = min(delay*2, RECONNECT_MAX_DELAY)
+	}
+}
+
+// setState updates the authorization flag and reports the state when it changed
+func (c *Client) setState(state AuthState) {
+	c.isAuth.Store(state == AUTH_STATE_READY)
+
+	c.mu.Lock()
+	changed := c.state != state
+	c.state = state
+	handler := c.onState
+	c.mu.Unlock()
+
+	if changed {
+		handler(state)
+	}
+}
+
+func (c *Client) userID() int64 {
+	if c.user == nil {
+		return 0
+	}
+
+	return c.user.Id
+}
@@ -0,0 +1,87 @@
+package timeTicker
+
+import (
+	"log/slog"
+	"math"
+	"time"
+)
+
+const DEFAULT_BACKOFF_MULTIPLIER = 2
+
+// Backoff delays the runs after consecutive failures: Initial after the first failure, multiplied after each next one up to Max
+type Backoff struct {
+	Initial    time.Duration
+	Max        time.Duration
+	Multiplier float64
+}
+
+func (b *Backoff) Delay(failures int) time.Duration {
+	if b == nil || failures <= 0 || b.Initial <= 0 {
+		return 0
+	}
+
+	multiplier := b.Multiplier
+	if multiplier < 1 {
+		multiplier = DEFAULT_BACKOFF_MULTIPLIER
+	}
+
+	delay := float64(b.Initial) * math.Pow(multiplier, float64(failures-1))
+	if b.Max > 0 && delay > float64(b.Max) {
+		return b.Max
+	}
+
+	return time.Duration(delay)
+}
+
+// failureStreak collapses consecutive failures into a few log lines: the first failure,
+// a changed error, reaching the max backoff and a summary once the runs succeed again
+type failureStreak struct {
+	count   int
+	since   time.Time
+	lastErr string
+	atMax   bool
+	until   time.Time
+	log     *slog.Logger
+	backoff *Backoff
+}
+
+func (s *failureStreak) failure(err error, at time.Time) {
+	s.count++
+	delay := s.backoff.Delay(s.count)
+	s.until = at.Add(delay)
+
+	message := err.Error()
+
+	switch {
+	case s.count == 1:
+		s.since = at
+		s.log.Warn("tick failed", "error", err, "backoff", delay)
+	case message != s.lastErr:
+		s.log.Warn("tick failing with a new error", "error", err, "failures", s.count, "backoff", delay)
+	case !s.atMax && s.backoff != nil && s.backoff.Max > 0 && delay >= s.backoff.Max:
+		s.atMax = true
+		s.log.Warn("tick still failing, backoff reached the max interval",
+			"error", err, "failures", s.count, "since", s.since, "backoff", delay)
+	default:
+		s.log.Debug("tick failed", "error", err, "failures", s.count, "backoff", delay)
+	}
+
+	s.lastErr = message
+}
+
+func (s *failureStreak) success(at time.Time) {
+	if s.count > 0 {
+		s.log.Info("tick recovered",
+			"failures", s.count, "since", s.since, "duration", at.Sub(s.since).Round(time.Second), "last_error", s.lastErr)
+	}
+
+	s.count = 0
+	s.atMax = false
+	s.lastErr = ""
+	s.until = time.Time{}
+}
+
+// notBefore returns the time the runs are held back until, zero when there is no backoff
+func (s *failureStreak) notBefore() time.Time {
+	return s.until
+}
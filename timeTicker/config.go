@@ -0,0 +1,116 @@
+package timeTicker
+
+import (
+	"errors"
+	"fmt"
+	"time"
+	"wb-assistance-logistic/config"
+)
+
+// NewScheduleByConfig builds the ticker schedule. Without cron expressions and windows it is a plain interval of cfg.Frequency ms,
+// windows override the frequency inside them, cron expressions fire in addition to the windows and holidays exclude whole dates.
+// With cron expressions the frequency is not used, so the windows fire only inside them.
+func NewScheduleByConfig(cfg *config.TimeTicker) (Schedule, error) {
+	if cfg == nil {
+		return nil, errors.New("ticker config can not be nil")
+	}
+
+	location := time.Local
+	if cfg.Timezone != "" {
+		var err error
+		location, err = time.LoadLocation(cfg.Timezone)
+		if err != nil {
+			return nil, fmt.Errorf("load timezone %q: %w", cfg.Timezone, err)
+		}
+	}
+
+	frequency := time.Duration(cfg.Frequency) * time.Millisecond
+
+	var schedules MultiSchedule
+
+	if len(cfg.Windows) > 0 {
+		windowSchedule := &WindowSchedule{Location: location}
+		if len(cfg.Cron) == 0 {
+			windowSchedule.Default = frequency
+		}
+
+		for i, w := range cfg.Windows {
+			window, err := newWindowByConfig(w)
+			if err != nil {
+				return nil, fmt.Errorf("ticker window %d: %w", i, err)
+			}
+			windowSchedule.Windows = append(windowSchedule.Windows, window)
+		}
+
+		schedules = append(schedules, windowSchedule)
+	} else if len(cfg.Cron) == 0 {
+		if frequency <= 0 {
+			return nil, fmt.Errorf("invalid ticker frequency %d", cfg.Frequency)
+		}
+		schedules = append(schedules, &IntervalSchedule{Interval: frequency})
+	}
+
+	for _, expression := range cfg.Cron {
+		cronSchedule, err := NewCronSchedule(expression, location)
+		if err != nil {
+			return nil, err
+		}
+		schedules = append(schedules, cronSchedule)
+	}
+
+	var schedule Schedule = schedules
+	if len(schedules) == 1 {
+		schedule = schedules[0]
+	}
+
+	if len(cfg.Holidays) == 0 {
+		return schedule, nil
+	}
+
+	holidays := make(map[string]bool, len(cfg.Holidays))
+	for _, date := range cfg.Holidays {
+		_, err := time.ParseInLocation(DATE_LAYOUT, date, location)
+		if err != nil {
+			return nil, fmt.Errorf("invalid holiday date %q, expected YYYY-MM-DD", date)
+		}
+		holidays[date] = true
+	}
+
+	return &HolidaySchedule{Schedule: schedule, Holidays: holidays, Location: location}, nil
+}
+
+func newWindowByConfig(cfg *config.TickerWindow) (*Window, error) {
+	if cfg == nil {
+		return nil, errors.New("window can not be nil")
+	}
+
+	from, err := ParseDayMinute(cfg.From)
+	if err != nil {
+		return nil, err
+	}
+
+	to, err := ParseDayMinute(cfg.To)
+	if err != nil {
+		return nil, err
+	}
+
+	if cfg.Frequency <= 0 {
+		return nil, fmt.Errorf("invalid window frequency %d", cfg.Frequency)
+	}
+
+	window := &Window{
+		From:     from,
+		To:       to,
+		Interval: time.Duration(cfg.Frequency) * time.Millisecond,
+	}
+
+	for _, day := range cfg.Days {
+		weekday, err := ParseWeekday(day)
+		if err != nil {
+			return nil, err
+		}
+		window.Days = append(window.Days, weekday)
+	}
+
+	return window, nil
+}
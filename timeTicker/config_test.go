@@ -0,0 +1,84 @@
+package timeTicker
+
+import (
+	"testing"
+	"time"
+	"wb-assistance-logistic/config"
+)
+
+func TestNewScheduleByConfig(t *testing.T) {
+	location := moscow(t)
+	morning := &config.TickerWindow{From: "07:30", To: "08:30", Frequency: 5000}
+
+	tests := []struct {
+		name  string
+		cfg   *config.TimeTicker
+		after time.Time
+		want  time.Time
+	}{
+		{
+			name:  "plain interval",
+			cfg:   &config.TimeTicker{Frequency: 60000, Timezone: "Europe/Moscow"},
+			after: at(location, 3, 10, 0),
+			want:  at(location, 3, 10, 1),
+		},
+		{
+			name:  "cron replaces the frequency",
+			cfg:   &config.TimeTicker{Frequency: 60000, Timezone: "Europe/Moscow", Cron: []string{"0 9 * * *"}},
+			after: at(location, 3, 10, 0),
+			want:  at(location, 4, 9, 0),
+		},
+		{
+			name:  "frequency outside the windows",
+			cfg:   &config.TimeTicker{Frequency: 60000, Timezone: "Europe/Moscow", Windows: []*config.TickerWindow{morning}},
+			after: at(location, 3, 10, 0),
+			want:  at(location, 3, 10, 1),
+		},
+		{
+			name:  "window frequency inside the window",
+			cfg:   &config.TimeTicker{Frequency: 60000, Timezone: "Europe/Moscow", Windows: []*config.TickerWindow{morning}},
+			after: at(location, 3, 8, 0),
+			want:  at(location, 3, 8, 0).Add(5 * time.Second),
+		},
+		{
+			name:  "cron and windows without the frequency",
+			cfg:   &config.TimeTicker{Frequency: 60000, Timezone: "Europe/Moscow", Cron: []string{"0 9 * * *"}, Windows: []*config.TickerWindow{morning}},
+			after: at(location, 3, 10, 0),
+			want:  at(location, 4, 7, 30),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			schedule, err := NewScheduleByConfig(tt.cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got := schedule.Next(tt.after); !got.Equal(tt.want) {
+				t.Errorf("Next(%s) = %s, want %s", tt.after, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewScheduleByConfigInvalid(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  *config.TimeTicker
+	}{
+		{"no config", nil},
+		{"no frequency", &config.TimeTicker{}},
+		{"unknown timezone", &config.TimeTicker{Frequency: 1000, Timezone: "Mars/Olympus"}},
+		{"invalid cron", &config.TimeTicker{Cron: []string{"* *"}}},
+		{"invalid holiday", &config.TimeTicker{Frequency: 1000, Holidays: []string{"01.01.2027"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewScheduleByConfig(tt.cfg); err == nil {
+				t.Error("NewScheduleByConfig returned no error")
+			}
+		})
+	}
+}
@@ -0,0 +1,304 @@
+package timeTicker
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/robfig/cron/v3"
+)
+
+const (
+	DATE_LAYOUT = "2006-01-02"
+	TIME_LAYOUT = "15:04"
+
+	// Limit of days searched for the next run, a schedule without a run in this period never fires
+	maxSearchDays = 400
+)
+
+// Schedule returns the next run time strictly after the given time, the zero time means there are no more runs
+type Schedule interface {
+	Next(after time.Time) time.Time
+}
+
+// activeSchedule is implemented by the schedules firing continuously during some periods
+type activeSchedule interface {
+	Active(t time.Time) bool
+}
+
+type IntervalSchedule struct {
+	Interval time.Duration
+}
+
+func (s *IntervalSchedule) Next(after time.Time) time.Time {
+	if s.Interval <= 0 {
+		return time.Time{}
+	}
+
+	return after.Add(s.Interval)
+}
+
+func (s *IntervalSchedule) Active(time.Time) bool {
+	return s.Interval > 0
+}
+
+type CronSchedule struct {
+	expression string
+	schedule   cron.Schedule
+	location   *time.Location
+}
+
+var cronParser = cron.NewParser(cron.SecondOptional | cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow | cron.Descriptor)
+
+// NewCronSchedule parses a standard cron expression with an optional leading seconds field, e.g. "*/30 8-20 * * 1-5"
+func NewCronSchedule(expression string, location *time.Location) (*CronSchedule, error) {
+	schedule, err := cronParser.Parse(expression)
+	if err != nil {
+		return nil, fmt.Errorf("parse cron expression %q: %w", expression, err)
+	}
+
+	if location == nil {
+		location = time.Local
+	}
+
+	return &CronSchedule{expression: expression, schedule: schedule, location: location}, nil
+}
+
+func (s *CronSchedule) Next(after time.Time) time.Time {
+	return s.schedule.Next(after.In(s.location))
+}
+
+func (s *CronSchedule) String() string {
+	return s.expression
+}
+
+// Window limits an interval to a part of the day. Minutes are counted from midnight,
+// a window with To <= From crosses midnight and belongs to the day it starts on.
+type Window struct {
+	Days     []time.Weekday // Empty means every day
+	From     int
+	To       int
+	Interval time.Duration
+}
+
+func (w *Window) hasDay(day time.Weekday) bool {
+	if len(w.Days) == 0 {
+		return true
+	}
+
+	for _, d := range w.Days {
+		if d == day {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (w *Window) crossesMidnight() bool {
+	return w.To <= w.From
+}
+
+func (w *Window) contains(t time.Time) bool {
+	minute := t.Hour()*60 + t.Minute()
+
+	if !w.crossesMidnight() {
+		return w.hasDay(t.Weekday()) && minute >= w.From && minute < w.To
+	}
+
+	if w.hasDay(t.Weekday()) && minute >= w.From {
+		return true
+	}
+
+	return w.hasDay(t.AddDate(0, 0, -1).Weekday()) && minute < w.To
+}
+
+// WindowSchedule fires with the interval of the first window containing the time,
+// outside of the windows it uses the default interval or waits for the next window when the default is zero.
+type WindowSchedule struct {
+	Windows  []*Window
+	Default  time.Duration
+	Location *time.Location
+}
+
+func (s *WindowSchedule) Next(after time.Time) time.Time {
+	after = after.In(s.location())
+
+	nextStart := s.nextWindowStart(after)
+
+	interval := s.interval(after)
+	if interval <= 0 {
+		return nextStart
+	}
+
+	next := after.Add(interval)
+
+	// Leaving the windows with no default interval waits for the next window
+	if s.Default <= 0 && s.window(next) == nil {
+		return nextStart
+	}
+
+	// A window starting earlier than the next run takes over with its own interval
+	if !nextStart.IsZero() && nextStart.Before(next) {
+		return nextStart
+	}
+
+	return next
+}
+
+func (s *WindowSchedule) Active(t time.Time) bool {
+	return s.interval(t.In(s.location())) > 0
+}
+
+func (s *WindowSchedule) location() *time.Location {
+	if s.Location == nil {
+		return time.Local
+	}
+
+	return s.Location
+}
+
+func (s *WindowSchedule) window(t time.Time) *Window {
+	for _, w := range s.Windows {
+		if w.contains(t) {
+			return w
+		}
+	}
+
+	return nil
+}
+
+func (s *WindowSchedule) interval(t time.Time) time.Duration {
+	if w := s.window(t); w != nil {
+		return w.Interval
+	}
+
+	return s.Default
+}
+
+func (s *WindowSchedule) nextWindowStart(after time.Time) time.Time {
+	var next time.Time
+
+	for _, w := range s.Windows {
+		for d := 0; d <= 7; d++ {
+			day := after.AddDate(0, 0, d)
+			if !w.hasDay(day.Weekday()) {
+				continue
+			}
+
+			start := time.Date(day.Year(), day.Month(), day.Day(), w.From/60, w.From%60, 0, 0, after.Location())
+			if start.After(after) {
+				if next.IsZero() || start.Before(next) {
+					next = start
+				}
+				break
+			}
+		}
+	}
+
+	return next
+}
+
+// MultiSchedule fires at the earliest next run of its schedules
+type MultiSchedule []Schedule
+
+func (s MultiSchedule) Active(t time.Time) bool {
+	for _, schedule := range s {
+		if active, ok := schedule.(activeSchedule); ok && active.Active(t) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (s MultiSchedule) Next(after time.Time) time.Time {
+	var next time.Time
+
+	for _, schedule := range s {
+		n := schedule.Next(after)
+		if !n.IsZero() && (next.IsZero() || n.Before(next)) {
+			next = n
+		}
+	}
+
+	return next
+}
+
+// HolidaySchedule skips the runs of the wrapped schedule falling on the excluded dates
+type HolidaySchedule struct {
+	Schedule Schedule
+	Holidays map[string]bool // Dates in the DATE_LAYOUT format
+	Location *time.Location
+}
+
+func (s *HolidaySchedule) Next(after time.Time) time.Time {
+	location := s.Location
+	if location == nil {
+		location = time.Local
+	}
+
+	limit := after.AddDate(0, 0, maxSearchDays)
+	next := after
+
+	for {
+		next = s.Schedule.Next(next)
+		if next.IsZero() || next.After(limit) {
+			return time.Time{}
+		}
+
+		local := next.In(location)
+		if !s.Holidays[local.Format(DATE_LAYOUT)] {
+			return next
+		}
+
+		// Jump to the end of the holiday instead of walking through every run of the day,
+		// a schedule which is active at midnight fires right when the holiday is over
+		midnight := time.Date(local.Year(), local.Month(), local.Day()+1, 0, 0, 0, 0, location)
+		if midnight.After(limit) {
+			return time.Time{}
+		}
+		if active, ok := s.Schedule.(activeSchedule); ok && active.Active(midnight) && !s.Holidays[midnight.Format(DATE_LAYOUT)] {
+			return midnight
+		}
+		next = midnight.Add(-time.Nanosecond)
+	}
+}
+
+func ParseWeekday(day string) (time.Weekday, error) {
+	days := map[string]time.Weekday{
+		"sun": time.Sunday,
+		"mon": time.Monday,
+		"tue": time.Tuesday,
+		"wed": time.Wednesday,
+		"thu": time.Thursday,
+		"fri": time.Friday,
+		"sat": time.Saturday,
+	}
+
+	key := strings.ToLower(day)
+	if len(key) > 3 {
+		key = key[:3]
+	}
+
+	weekday, ok := days[key]
+	if !ok {
+		return 0, fmt.Errorf("unknown weekday %q", day)
+	}
+
+	return weekday, nil
+}
+
+// ParseDayMinute converts "HH:MM" to minutes since midnight, "24:00" is accepted as the end of the day
+func ParseDayMinute(value string) (int, error) {
+	if value == "24:00" {
+		return 24 * 60, nil
+	}
+
+	t, err := time.Parse(TIME_LAYOUT, value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time of day %q, expected HH:MM", value)
+	}
+
+	return t.Hour()*60 + t.Minute(), nil
+}
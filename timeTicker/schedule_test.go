@@ -0,0 +1,278 @@
+package timeTicker
+
+import (
+	"testing"
+	"time"
+)
+
+func moscow(t *testing.T) *time.Location {
+	t.Helper()
+
+	location, err := time.LoadLocation("Europe/Moscow")
+	if err != nil {
+		t.Fatalf("load location: %v", err)
+	}
+
+	return location
+}
+
+// 2024-06-03 is a Monday
+func at(location *time.Location, day, hour, minute int) time.Time {
+	return time.Date(2024, time.June, day, hour, minute, 0, 0, location)
+}
+
+func TestWindowContains(t *testing.T) {
+	location := moscow(t)
+
+	day := &Window{From: 8 * 60, To: 20 * 60}
+	night := &Window{Days: []time.Weekday{time.Monday}, From: 22 * 60, To: 2 * 60}
+	allDay := &Window{From: 0, To: 0}
+
+	tests := []struct {
+		name   string
+		window *Window
+		time   time.Time
+		want   bool
+	}{
+		{"before the day window", day, at(location, 3, 7, 59), false},
+		{"start of the day window", day, at(location, 3, 8, 0), true},
+		{"end of the day window", day, at(location, 3, 19, 59), true},
+		{"end is exclusive", day, at(location, 3, 20, 0), false},
+		{"night window start", night, at(location, 3, 22, 0), true},
+		{"night window before midnight", night, at(location, 3, 23, 59), true},
+		{"night window after midnight belongs to the previous day", night, at(location, 4, 1, 59), true},
+		{"night window end is exclusive", night, at(location, 4, 2, 0), false},
+		{"night window on another day", night, at(location, 4, 22, 30), false},
+		{"night window morning of its own day", night, at(location, 3, 1, 0), false},
+		{"night window on sunday", night, at(location, 2, 23, 0), false},
+		{"equal bounds cover the whole day", allDay, at(location, 5, 13, 37), true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.window.contains(tt.time); got != tt.want {
+				t.Errorf("contains(%s) = %v, want %v", tt.time, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWindowScheduleNext(t *testing.T) {
+	location := moscow(t)
+
+	day := &Window{From: 8 * 60, To: 20 * 60, Interval: 10 * time.Minute}
+	friday := &Window{Days: []time.Weekday{time.Friday}, From: 22 * 60, To: 2 * 60, Interval: 30 * time.Minute}
+
+	tests := []struct {
+		name     string
+		schedule *WindowSchedule
+		after    time.Time
+		want     time.Time
+	}{
+		{
+			name:     "inside the window",
+			schedule: &WindowSchedule{Windows: []*Window{day}, Location: location},
+			after:    at(location, 3, 9, 0),
+			want:     at(location, 3, 9, 10),
+		},
+		{
+			name:     "leaving the window waits for the next day",
+			schedule: &WindowSchedule{Windows: []*Window{day}, Location: location},
+			after:    at(location, 3, 19, 55),
+			want:     at(location, 4, 8, 0),
+		},
+		{
+			name:     "before the window without a default",
+			schedule: &WindowSchedule{Windows: []*Window{day}, Location: location},
+			after:    at(location, 3, 6, 0),
+			want:     at(location, 3, 8, 0),
+		},
+		{
+			name:     "default interval outside the window",
+			schedule: &WindowSchedule{Windows: []*Window{day}, Default: time.Hour, Location: location},
+			after:    at(location, 3, 6, 30),
+			want:     at(location, 3, 7, 30),
+		},
+		{
+			name:     "window start takes over the default interval",
+			schedule: &WindowSchedule{Windows: []*Window{day}, Default: time.Hour, Location: location},
+			after:    at(location, 3, 7, 30),
+			want:     at(location, 3, 8, 0),
+		},
+		{
+			name:     "window crossing midnight",
+			schedule: &WindowSchedule{Windows: []*Window{friday}, Location: location},
+			after:    at(location, 7, 23, 45),
+			want:     at(location, 8, 0, 15),
+		},
+		{
+			name:     "window crossing midnight waits for the next week",
+			schedule: &WindowSchedule{Windows: []*Window{friday}, Location: location},
+			after:    at(location, 8, 1, 45),
+			want:     at(location, 14, 22, 0),
+		},
+		{
+			name:     "time in another location",
+			schedule: &WindowSchedule{Windows: []*Window{day}, Location: location},
+			after:    time.Date(2024, time.June, 3, 4, 0, 0, 0, time.UTC),
+			want:     at(location, 3, 8, 0),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.schedule.Next(tt.after); !got.Equal(tt.want) {
+				t.Errorf("Next(%s) = %s, want %s", tt.after, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHolidayScheduleNext(t *testing.T) {
+	location := moscow(t)
+
+	daily, err := NewCronSchedule("0 9 * * *", location)
+	if err != nil {
+		t.Fatal(err)
+	}
+	newYear, err := NewCronSchedule("0 9 1 1 *", location)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		schedule *HolidaySchedule
+		after    time.Time
+		want     time.Time
+	}{
+		{
+			name:     "no holiday",
+			schedule: &HolidaySchedule{Schedule: daily, Location: location},
+			after:    at(location, 3, 10, 0),
+			want:     at(location, 4, 9, 0),
+		},
+		{
+			name:     "skips consecutive holidays",
+			schedule: &HolidaySchedule{Schedule: daily, Holidays: map[string]bool{"2024-06-04": true, "2024-06-05": true}, Location: location},
+			after:    at(location, 3, 10, 0),
+			want:     at(location, 6, 9, 0),
+		},
+		{
+			name:     "active schedule fires at the end of the holiday",
+			schedule: &HolidaySchedule{Schedule: &IntervalSchedule{Interval: time.Hour}, Holidays: map[string]bool{"2024-06-04": true}, Location: location},
+			after:    at(location, 3, 23, 30),
+			want:     at(location, 5, 0, 0),
+		},
+		{
+			name:     "holidays are dates of the schedule location",
+			schedule: &HolidaySchedule{Schedule: &IntervalSchedule{Interval: time.Hour}, Holidays: map[string]bool{"2024-06-04": true}, Location: location},
+			after:    time.Date(2024, time.June, 3, 20, 30, 0, 0, time.UTC),
+			want:     at(location, 5, 0, 0),
+		},
+		{
+			name:     "run within the search limit",
+			schedule: &HolidaySchedule{Schedule: newYear, Location: location},
+			after:    at(location, 3, 10, 0),
+			want:     time.Date(2025, time.January, 1, 9, 0, 0, 0, location),
+		},
+		{
+			name:     "no run within the search limit",
+			schedule: &HolidaySchedule{Schedule: newYear, Holidays: map[string]bool{"2025-01-01": true}, Location: location},
+			after:    at(location, 3, 10, 0),
+			want:     time.Time{},
+		},
+		{
+			name:     "wrapped schedule without runs",
+			schedule: &HolidaySchedule{Schedule: &IntervalSchedule{}, Location: location},
+			after:    at(location, 3, 10, 0),
+			want:     time.Time{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.schedule.Next(tt.after); !got.Equal(tt.want) {
+				t.Errorf("Next(%s) = %s, want %s", tt.after, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHolidayScheduleSearchLimit(t *testing.T) {
+	location := moscow(t)
+	after := at(location, 3, 10, 0)
+
+	// Every date of the limit is a holiday, the next run after it is out of the search
+	holidays := map[string]bool{}
+	for d := 0; d <= maxSearchDays; d++ {
+		holidays[after.AddDate(0, 0, d).Format(DATE_LAYOUT)] = true
+	}
+
+	schedule := &HolidaySchedule{Schedule: &IntervalSchedule{Interval: time.Hour}, Holidays: holidays, Location: location}
+	if got := schedule.Next(after); !got.IsZero() {
+		t.Errorf("Next(%s) = %s, want the zero time", after, got)
+	}
+}
+
+func TestCronScheduleNext(t *testing.T) {
+	location := moscow(t)
+
+	tests := []struct {
+		name       string
+		expression string
+		location   *time.Location
+		after      time.Time
+		want       time.Time
+	}{
+		{
+			name:       "daily run in the schedule location",
+			expression: "0 9 * * *",
+			location:   location,
+			after:      time.Date(2024, time.June, 3, 5, 0, 0, 0, time.UTC),
+			want:       time.Date(2024, time.June, 3, 6, 0, 0, 0, time.UTC),
+		},
+		{
+			name:       "same expression in utc",
+			expression: "0 9 * * *",
+			location:   time.UTC,
+			after:      time.Date(2024, time.June, 3, 5, 0, 0, 0, time.UTC),
+			want:       time.Date(2024, time.June, 3, 9, 0, 0, 0, time.UTC),
+		},
+		{
+			name:       "working hours skip the weekend",
+			expression: "*/30 8-20 * * 1-5",
+			location:   location,
+			after:      at(location, 7, 20, 30),
+			want:       at(location, 10, 8, 0),
+		},
+		{
+			name:       "optional seconds field",
+			expression: "30 0 9 * * *",
+			location:   location,
+			after:      at(location, 3, 9, 0),
+			want:       at(location, 3, 9, 0).Add(30 * time.Second),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			schedule, err := NewCronSchedule(tt.expression, tt.location)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got := schedule.Next(tt.after); !got.Equal(tt.want) {
+				t.Errorf("Next(%s) = %s, want %s", tt.after, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewCronScheduleInvalid(t *testing.T) {
+	for _, expression := range []string{"", "* * *", "61 * * * *", "0 9 * * mon-xyz"} {
+		if _, err := NewCronSchedule(expression, time.UTC); err == nil {
+			t.Errorf("NewCronSchedule(%q) returned no error", expression)
+		}
+	}
+}
@@ -1,71 +1,452 @@
 package timeTicker
 
-import "time"
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/rand"
+	"sync"
+	"time"
+	"wb-assistance-logistic/config"
+	"wb-assistance-logistic/logger"
+)
 
+// OverlapPolicy defines what happens when a run is due while the previous one is still in progress
+type OverlapPolicy string
+
+const (
+	OVERLAP_SKIP   OverlapPolicy = "skip"   // The due run is dropped
+	OVERLAP_QUEUE  OverlapPolicy = "queue"  // One run is started right after the current one finishes
+	OVERLAP_CANCEL OverlapPolicy = "cancel" // The context of the current run is canceled and a new run starts after it returns
+)
+
+type Callback func(ctx context.Context) error
+
+// RetryAfterError is implemented by the callback errors asking not to run again before a delay,
+// e.g. a rate limit of a remote service. The delay is honored on top of the backoff.
+type RetryAfterError interface {
+	error
+	RetryAfter() time.Duration
+}
+
+// TickReport describes a finished run
+type TickReport struct {
+	Start    time.Time
+	Duration time.Duration
+	Err      error
+	Canceled bool
+}
+
+// TimeTicker runs the callback by the schedule in its own goroutine.
+// All methods are safe for concurrent use, including from the callback and the report handler.
 type TimeTicker struct {
-	ticker    *time.Ticker
-	duration  time.Duration
-	frequency time.Duration
-	callback  func()
-	chanStop  chan struct{}
+	mu        sync.Mutex
+	schedule  Schedule
+	jitter    time.Duration
+	backoff   *Backoff
+	policy    OverlapPolicy
+	callback  Callback
+	onReport  func(TickReport)
+	cancel    context.CancelFunc
+	done      chan struct{}
 	isStarted bool
+
+	chanTrigger chan struct{}
+	chanReload  chan struct{}
+	log         *slog.Logger
 }
 
+// NewTimeTicker creates a ticker firing every frequency milliseconds
 func NewTimeTicker(frequency int) *TimeTicker {
+	return NewTimeTickerBySchedule(&IntervalSchedule{Interval: time.Duration(frequency) * time.Millisecond})
+}
+
+func NewTimeTickerBySchedule(schedule Schedule) *TimeTicker {
+	done := make(chan struct{})
+	close(done)
+
 	return &TimeTicker{
-		ticker:    time.NewTicker(time.Duration(frequency) * time.Millisecond),
-		duration:  time.Millisecond,
-		frequency: time.Duration(frequency) * time.Millisecond,
-		callback:  func() {},
-		chanStop:  make(chan struct{}),
-		isStarted: false,
+		schedule:    schedule,
+		policy:      OVERLAP_SKIP,
+		callback:    func(context.Context) error { return nil },
+		onReport:    func(TickReport) {},
+		done:        done,
+		isStarted:   false,
+		chanTrigger: make(chan struct{}, 1),
+		chanReload:  make(chan struct{}, 1),
+		log:         logger.With("component", "ticker"),
 	}
 }
 
-func (t *TimeTicker) SetCallback(cb func()) {
+func NewTimeTickerByConfig(cfg *config.TimeTicker) (*TimeTicker, error) {
+	schedule, err := NewScheduleByConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	t := NewTimeTickerBySchedule(schedule)
+
+	if cfg.OverlapPolicy != "" {
+		err = t.SetOverlapPolicy(OverlapPolicy(cfg.OverlapPolicy))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if cfg.Jitter < 0 {
+		return nil, fmt.Errorf("invalid ticker jitter %d", cfg.Jitter)
+	}
+	t.jitter = time.Duration(cfg.Jitter) * time.Millisecond
+
+	if cfg.Backoff != nil {
+		if cfg.Backoff.Initial < 0 || cfg.Backoff.Max < 0 || cfg.Backoff.Multiplier < 0 {
+			return nil, fmt.Errorf("invalid ticker backoff %+v", *cfg.Backoff)
+		}
+
+		t.backoff = &Backoff{
+			Initial:    time.Duration(cfg.Backoff.Initial) * time.Millisecond,
+			Max:        time.Duration(cfg.Backoff.Max) * time.Millisecond,
+			Multiplier: cfg.Backoff.Multiplier,
+		}
+	}
+
+	return t, nil
+}
+
+func (t *TimeTicker) SetCallback(cb Callback) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
 	t.callback = cb
 }
 
+// SetReportHandler sets the function receiving the report of every finished run, it is called from the ticker goroutine
+func (t *TimeTicker) SetReportHandler(handler func(TickReport)) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.onReport = handler
+}
+
+func (t *TimeTicker) SetOverlapPolicy(policy OverlapPolicy) error {
+	switch policy {
+	case OVERLAP_SKIP, OVERLAP_QUEUE, OVERLAP_CANCEL:
+	default:
+		return fmt.Errorf("unknown overlap policy %q", policy)
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.policy = policy
+	return nil
+}
+
+// SetJitter sets the upper bound of a random delay added to every scheduled run
+func (t *TimeTicker) SetJitter(jitter time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.jitter = jitter
+}
+
+// SetBackoff sets the delay of the runs after consecutive failed runs, nil disables the backoff.
+// It applies from the next Start.
+func (t *TimeTicker) SetBackoff(backoff *Backoff) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.backoff = backoff
+}
+
+// SetSchedule replaces the schedule, a started ticker plans its next run by the new schedule right away
+func (t *TimeTicker) SetSchedule(schedule Schedule) {
+	t.mu.Lock()
+	t.schedule = schedule
+	t.mu.Unlock()
+
+	notify(t.chanReload)
+}
+
+// Reset replaces the schedule with a plain interval of frequency milliseconds
+func (t *TimeTicker) Reset(frequency int) {
+	if frequency <= 0 {
+		return
+	}
+
+	t.SetSchedule(&IntervalSchedule{Interval: time.Duration(frequency) * time.Millisecond})
+}
+
+// Start runs the ticker until Stop is called
 func (t *TimeTicker) Start() {
+	t.StartContext(context.Background())
+}
+
+// StartContext runs the ticker until Stop is called or the context is done.
+// A restarted ticker waits for the run left by the previous start, so runs never overlap across restarts.
+func (t *TimeTicker) StartContext(ctx context.Context) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
 	if t.isStarted {
 		return
 	}
 
+	ctx, cancel := context.WithCancel(ctx)
+	previous := t.done
+	done := make(chan struct{})
+
+	t.cancel = cancel
+	t.done = done
 	t.isStarted = true
-	t.ticker.Reset(t.frequency)
 
 	go func() {
-		for {
-			select {
-			case <-t.ticker.C:
-				t.callback()
-			case <-t.chanStop:
-				t.ticker.Stop()
-				return
-			}
+		defer close(done)
+		defer t.markStopped(done)
+
+		select {
+		case <-previous:
+		case <-ctx.Done():
+			return
 		}
+
+		t.run(ctx)
 	}()
 }
 
+// Stop cancels the ticker and the context of the current run without waiting for it, so it can be called from the callback
 func (t *TimeTicker) Stop() {
-	if t.isStarted {
-		t.chanStop <- struct{}{}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if !t.isStarted {
+		return
+	}
+
+	t.cancel()
+	t.isStarted = false
+}
+
+// Done returns a channel closed when the ticker started last has stopped and its run has returned
+func (t *TimeTicker) Done() <-chan struct{} {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return t.done
+}
+
+// TriggerNow starts a run immediately (subject to the overlap policy) and reschedules the next run relative to now
+func (t *TimeTicker) TriggerNow() {
+	notify(t.chanTrigger)
+}
+
+// NextRun returns the time the schedule fires after now, zero when it never fires again
+func (t *TimeTicker) NextRun() time.Time {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return t.schedule.Next(time.Now())
+}
+
+func (t *TimeTicker) IsStarted() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return t.isStarted
+}
+
+// markStopped clears the started flag when the loop ends by itself, e.g. after the parent context is done
+func (t *TimeTicker) markStopped(done chan struct{}) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.done == done {
 		t.isStarted = false
 	}
 }
 
-func (t *TimeTicker) Reset(frequency int) {
-	if frequency <= 0 {
-		return
+func (t *TimeTicker) run(ctx context.Context) {
+	t.mu.Lock()
+	streak := &failureStreak{log: t.log, backoff: t.backoff}
+	t.mu.Unlock()
+
+	var (
+		running    bool
+		queued     bool
+		retryAfter time.Time
+		cancelRun  context.CancelFunc
+		reports    = make(chan TickReport, 1)
+		timer      *time.Timer
+		timerC     <-chan time.Time
+		next       time.Time
+	)
+
+	notBefore := func() time.Time {
+		if retryAfter.After(streak.notBefore()) {
+			return retryAfter
+		}
+		return streak.notBefore()
 	}
 
-	t.frequency = time.Duration(frequency) * t.duration
-	if t.isStarted {
-		t.Stop()
-		t.Start()
+	schedule := func(planned time.Time) {
+		if timer != nil {
+			timer.Stop()
+		}
+		timer, timerC = nil, nil
+
+		next = t.nextRun(planned)
+		if next.IsZero() {
+			return
+		}
+
+		if notBefore := notBefore(); next.Before(notBefore) {
+			next = t.runAt(notBefore)
+			if next.IsZero() {
+				return
+			}
+		}
+
+		timer = time.NewTimer(time.Until(next) + t.randomJitter())
+		timerC = timer.C
+	}
+
+	start := func() {
+		runCtx, cancel := context.WithCancel(ctx)
+		cancelRun = cancel
+		running = true
+
+		t.mu.Lock()
+		callback := t.callback
+		t.mu.Unlock()
+
+		go func() {
+			begin := time.Now()
+			err := callback(runCtx)
+			canceled := errors.Is(runCtx.Err(), context.Canceled)
+			cancel()
+
+			reports <- TickReport{Start: begin, Duration: time.Since(begin), Err: err, Canceled: canceled}
+		}()
+	}
+
+	fire := func() {
+		if !running {
+			start()
+			return
+		}
+
+		t.mu.Lock()
+		policy := t.policy
+		t.mu.Unlock()
+
+		switch policy {
+		case OVERLAP_SKIP:
+			t.log.Warn("previous tick is still running, skipping")
+		case OVERLAP_QUEUE:
+			queued = true
+		case OVERLAP_CANCEL:
+			t.log.Warn("previous tick is still running, canceling it")
+			cancelRun()
+			queued = true
+		}
+	}
+
+	schedule(time.Now())
+
+	for {
+		select {
+		case <-timerC:
+			schedule(next)
+			fire()
+		case <-t.chanTrigger:
+			schedule(time.Now())
+			fire()
+		case <-t.chanReload:
+			schedule(time.Now())
+		case report := <-reports:
+			running = false
+
+			t.mu.Lock()
+			onReport := t.onReport
+			t.mu.Unlock()
+			onReport(report)
+
+			var retryErr RetryAfterError
+			if errors.As(report.Err, &retryErr) && retryErr.RetryAfter() > 0 {
+				retryAfter = time.Now().Add(retryErr.RetryAfter())
+				t.log.Warn("tick asked to retry later", "retry_after", retryErr.RetryAfter())
+			}
+
+			switch {
+			case report.Err == nil:
+				streak.success(time.Now())
+			case !report.Canceled:
+				streak.failure(report.Err, time.Now())
+				if !notBefore().IsZero() && next.Before(notBefore()) {
+					schedule(time.Now())
+				}
+			}
+
+			if queued {
+				queued = false
+				// A queued run waits for the backoff like any scheduled run
+				if !time.Now().Before(notBefore()) {
+					start()
+				}
+			}
+		case <-ctx.Done():
+			if timer != nil {
+				timer.Stop()
+			}
+			if running {
+				cancelRun()
+				<-reports
+			}
+			return
+		}
 	}
 }
 
-func (t *TimeTicker) IsStarted() bool {
-	return t.isStarted
+// nextRun keeps the schedule rate after the planned run time, runs missed because of a slow callback are dropped
+func (t *TimeTicker) nextRun(planned time.Time) time.Time {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	next := t.schedule.Next(planned)
+	if !next.IsZero() && next.Before(time.Now()) {
+		next = t.schedule.Next(time.Now())
+	}
+
+	return next
+}
+
+// runAt returns the time itself when the schedule is running at that moment, otherwise the next run after it
+func (t *TimeTicker) runAt(at time.Time) time.Time {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if active, ok := t.schedule.(activeSchedule); ok && active.Active(at) {
+		return at
+	}
+
+	return t.schedule.Next(at)
+}
+
+func (t *TimeTicker) randomJitter() time.Duration {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.jitter <= 0 {
+		return 0
+	}
+
+	return time.Duration(rand.Int63n(int64(t.jitter)))
+}
+
+func notify(ch chan struct{}) {
+	select {
+	case ch <- struct{}{}:
+	default:
+	}
 }
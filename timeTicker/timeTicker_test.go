@@ -0,0 +1,283 @@
+package timeTicker
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+const testTimeout = 2 * time.Second
+
+type retryError struct {
+	after time.Duration
+}
+
+func (e *retryError) Error() string {
+	return "retry later"
+}
+
+func (e *retryError) RetryAfter() time.Duration {
+	return e.after
+}
+
+func waitDone(t *testing.T, ticker *TimeTicker) {
+	t.Helper()
+
+	select {
+	case <-ticker.Done():
+	case <-time.After(testTimeout):
+		t.Fatal("ticker did not stop")
+	}
+}
+
+func receive[T any](t *testing.T, ch <-chan T, what string) T {
+	t.Helper()
+
+	select {
+	case v := <-ch:
+		return v
+	case <-time.After(testTimeout):
+		t.Fatalf("timed out waiting for %s", what)
+	}
+
+	var zero T
+	return zero
+}
+
+// hourly returns a ticker which runs only when triggered during a test
+func hourly() *TimeTicker {
+	return NewTimeTickerBySchedule(&IntervalSchedule{Interval: time.Hour})
+}
+
+func TestStopFromCallback(t *testing.T) {
+	ticker := NewTimeTicker(5)
+
+	var calls atomic.Int32
+	ticker.SetCallback(func(context.Context) error {
+		calls.Add(1)
+		ticker.Stop()
+		return nil
+	})
+
+	ticker.Start()
+	waitDone(t, ticker)
+
+	if ticker.IsStarted() {
+		t.Error("ticker is still started")
+	}
+	if got := calls.Load(); got != 1 {
+		t.Errorf("callback called %d times, want 1", got)
+	}
+}
+
+func TestConcurrentControl(t *testing.T) {
+	ticker := NewTimeTicker(1)
+
+	var calls atomic.Int32
+	ticker.SetCallback(func(ctx context.Context) error {
+		calls.Add(1)
+		select {
+		case <-ctx.Done():
+		case <-time.After(time.Millisecond):
+		}
+		return nil
+	})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			for j := 0; j < 100; j++ {
+				switch (i + j) % 4 {
+				case 0:
+					ticker.Start()
+				case 1:
+					ticker.Stop()
+				case 2:
+					ticker.Reset(1 + j%5)
+				case 3:
+					ticker.TriggerNow()
+				}
+				_ = ticker.NextRun()
+				_ = ticker.IsStarted()
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	ticker.Start()
+	ticker.TriggerNow()
+	ticker.Stop()
+	waitDone(t, ticker)
+
+	if ticker.IsStarted() {
+		t.Error("ticker is still started")
+	}
+}
+
+func TestOverlapPolicy(t *testing.T) {
+	tests := []struct {
+		policy       OverlapPolicy
+		wantCalls    int32
+		wantCanceled bool
+	}{
+		{OVERLAP_SKIP, 1, false},
+		{OVERLAP_QUEUE, 2, false},
+		{OVERLAP_CANCEL, 2, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.policy), func(t *testing.T) {
+			ticker := hourly()
+			if err := ticker.SetOverlapPolicy(tt.policy); err != nil {
+				t.Fatal(err)
+			}
+
+			started := make(chan struct{}, 2)
+			release := make(chan struct{})
+			reports := make(chan TickReport, 2)
+
+			var calls atomic.Int32
+			ticker.SetCallback(func(ctx context.Context) error {
+				calls.Add(1)
+				started <- struct{}{}
+				select {
+				case <-ctx.Done():
+				case <-release:
+				}
+				return nil
+			})
+			ticker.SetReportHandler(func(report TickReport) {
+				reports <- report
+			})
+
+			ticker.Start()
+			defer func() {
+				ticker.Stop()
+				waitDone(t, ticker)
+			}()
+
+			ticker.TriggerNow()
+			receive(t, started, "the first run")
+
+			// The second trigger is due while the first run is in progress
+			ticker.TriggerNow()
+			time.Sleep(50 * time.Millisecond)
+
+			if tt.policy != OVERLAP_CANCEL {
+				release <- struct{}{}
+			}
+
+			first := receive(t, reports, "the first report")
+			if first.Canceled != tt.wantCanceled {
+				t.Errorf("first run canceled = %v, want %v", first.Canceled, tt.wantCanceled)
+			}
+
+			if tt.wantCalls > 1 {
+				receive(t, started, "the second run")
+				release <- struct{}{}
+				receive(t, reports, "the second report")
+			} else {
+				time.Sleep(50 * time.Millisecond)
+			}
+
+			if got := calls.Load(); got != tt.wantCalls {
+				t.Errorf("callback called %d times, want %d", got, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestSetOverlapPolicyUnknown(t *testing.T) {
+	if err := hourly().SetOverlapPolicy("wait"); err == nil {
+		t.Error("unknown policy returned no error")
+	}
+}
+
+func TestBackoffDelay(t *testing.T) {
+	tests := []struct {
+		name     string
+		backoff  *Backoff
+		failures int
+		want     time.Duration
+	}{
+		{"nil backoff", nil, 3, 0},
+		{"no failures", &Backoff{Initial: time.Second}, 0, 0},
+		{"no initial delay", &Backoff{Max: time.Second}, 2, 0},
+		{"first failure", &Backoff{Initial: 100 * time.Millisecond}, 1, 100 * time.Millisecond},
+		{"default multiplier", &Backoff{Initial: 100 * time.Millisecond}, 3, 400 * time.Millisecond},
+		{"custom multiplier", &Backoff{Initial: 100 * time.Millisecond, Multiplier: 1.5}, 3, 225 * time.Millisecond},
+		{"multiplier below one", &Backoff{Initial: 100 * time.Millisecond, Multiplier: 0.5}, 2, 200 * time.Millisecond},
+		{"capped by max", &Backoff{Initial: 100 * time.Millisecond, Max: 300 * time.Millisecond}, 3, 300 * time.Millisecond},
+		{"max without overflow", &Backoff{Initial: time.Second, Max: time.Minute}, 1000, time.Minute},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.backoff.Delay(tt.failures); got != tt.want {
+				t.Errorf("Delay(%d) = %s, want %s", tt.failures, got, tt.want)
+			}
+		})
+	}
+}
+
+// The run after a failure waits for the longer of the backoff and the retry after hold
+func TestFailureHold(t *testing.T) {
+	tests := []struct {
+		name       string
+		backoff    *Backoff
+		retryAfter time.Duration
+		wantHold   time.Duration
+	}{
+		{"backoff only", &Backoff{Initial: 150 * time.Millisecond}, 0, 150 * time.Millisecond},
+		{"retry after only", nil, 150 * time.Millisecond, 150 * time.Millisecond},
+		{"backoff longer than retry after", &Backoff{Initial: 200 * time.Millisecond}, 50 * time.Millisecond, 200 * time.Millisecond},
+		{"retry after longer than backoff", &Backoff{Initial: 20 * time.Millisecond}, 200 * time.Millisecond, 200 * time.Millisecond},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ticker := NewTimeTicker(10)
+			ticker.SetBackoff(tt.backoff)
+
+			reports := make(chan TickReport, 2)
+			var calls atomic.Int32
+			ticker.SetCallback(func(context.Context) error {
+				if calls.Add(1) > 1 {
+					return nil
+				}
+				if tt.retryAfter > 0 {
+					return &retryError{after: tt.retryAfter}
+				}
+				return errors.New("failed")
+			})
+			ticker.SetReportHandler(func(report TickReport) {
+				select {
+				case reports <- report:
+				default:
+				}
+			})
+
+			ticker.Start()
+			defer func() {
+				ticker.Stop()
+				waitDone(t, ticker)
+			}()
+
+			first := receive(t, reports, "the failed run")
+			second := receive(t, reports, "the run after the hold")
+
+			if first.Err == nil {
+				t.Fatal("first run did not fail")
+			}
+			if hold := second.Start.Sub(first.Start.Add(first.Duration)); hold < tt.wantHold {
+				t.Errorf("next run started %s after the failure, want at least %s", hold, tt.wantHold)
+			}
+		})
+	}
+}
@@ -0,0 +1,114 @@
+package transform
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"wb-assistance-logistic/config"
+	"wb-assistance-logistic/utils"
+)
+
+type AggregateType string
+
+const (
+	SUM_AGGREGATE   AggregateType = "sum"
+	MIN_AGGREGATE   AggregateType = "min"
+	MAX_AGGREGATE   AggregateType = "max"
+	AVG_AGGREGATE   AggregateType = "avg"
+	COUNT_AGGREGATE AggregateType = "count" // Rows meeting the condition, e.g. parkings over a threshold
+)
+
+// Aggregate is a value computed over all the rows of a tick
+type Aggregate struct {
+	Name  string
+	Type  AggregateType
+	Field string
+	Op    string
+	Value float64
+
+	fieldIndex int
+}
+
+func newAggregateByConfig(cfg *config.TransformAggregate, columns []string) (*Aggregate, error) {
+	if cfg == nil {
+		return nil, errors.New("aggregate can not be nil")
+	}
+
+	if cfg.Name == "" {
+		return nil, errors.New("aggregate name can not be empty")
+	}
+
+	aggregate := &Aggregate{
+		Name:       cfg.Name,
+		Type:       AggregateType(cfg.Type),
+		Field:      cfg.Field,
+		Op:         cfg.Op,
+		fieldIndex: slices.Index(columns, cfg.Field),
+	}
+
+	switch aggregate.Type {
+	case SUM_AGGREGATE, MIN_AGGREGATE, MAX_AGGREGATE, AVG_AGGREGATE:
+	case COUNT_AGGREGATE:
+		if aggregate.Op == "" {
+			return aggregate, nil
+		}
+		if cfg.Value == nil {
+			return nil, fmt.Errorf("aggregate %q: condition has no value", aggregate.Name)
+		}
+		if _, err := utils.Compare(0, aggregate.Op, 0); err != nil {
+			return nil, fmt.Errorf("aggregate %q: %w", aggregate.Name, err)
+		}
+		aggregate.Value = *cfg.Value
+	default:
+		return nil, fmt.Errorf("aggregate %q: unknown type %q", aggregate.Name, cfg.Type)
+	}
+
+	if aggregate.fieldIndex < 0 {
+		return nil, fmt.Errorf("aggregate %q: unknown field %q", aggregate.Name, cfg.Field)
+	}
+
+	return aggregate, nil
+}
+
+// value computes the aggregate, the empty cell means there are no rows to take the min, max or average of
+func (a *Aggregate) value(rows [][]int) interface{} {
+	if a.Type == COUNT_AGGREGATE {
+		count := 0
+		for _, row := range rows {
+			if a.Op == "" {
+				count++
+				continue
+			}
+			if ok, _ := utils.Compare(float64(row[a.fieldIndex]), a.Op, a.Value); ok {
+				count++
+			}
+		}
+		return count
+	}
+
+	if len(rows) == 0 {
+		if a.Type == SUM_AGGREGATE {
+			return 0
+		}
+		return ""
+	}
+
+	sum, low, high := 0, rows[0][a.fieldIndex], rows[0][a.fieldIndex]
+	for _, row := range rows {
+		value := row[a.fieldIndex]
+		sum += value
+		low = min(low, value)
+		high = max(high, value)
+	}
+
+	switch a.Type {
+	case MIN_AGGREGATE:
+		return low
+	case MAX_AGGREGATE:
+		return high
+	case AVG_AGGREGATE:
+		return round(float64(sum)/float64(len(rows)), DEFAULT_PRECISION)
+	}
+
+	return sum
+}
@@ -0,0 +1,98 @@
+package transform
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"wb-assistance-logistic/config"
+)
+
+type ColumnType string
+
+const (
+	RATIO_COLUMN ColumnType = "ratio" // The field divided by another field, e.g. boxes per barcode
+	DELTA_COLUMN ColumnType = "delta" // The change of the field since the previous tick
+)
+
+const DEFAULT_PRECISION = 2
+
+// Column is a value computed for every row, the empty cell means the value is undefined,
+// e.g. a division by zero or a key missing in the previous tick
+type Column struct {
+	Name      string
+	Type      ColumnType
+	Field     string
+	By        string
+	Precision int
+
+	fieldIndex int
+	byIndex    int
+}
+
+func newColumnByConfig(cfg *config.TransformColumn, columns []string) (*Column, error) {
+	if cfg == nil {
+		return nil, errors.New("column can not be nil")
+	}
+
+	if cfg.Name == "" {
+		return nil, errors.New("column name can not be empty")
+	}
+
+	column := &Column{
+		Name:       cfg.Name,
+		Type:       ColumnType(cfg.Type),
+		Field:      cfg.Field,
+		By:         cfg.By,
+		Precision:  DEFAULT_PRECISION,
+		fieldIndex: slices.Index(columns, cfg.Field),
+		byIndex:    -1,
+	}
+
+	if cfg.Precision != nil {
+		if *cfg.Precision < 0 {
+			return nil, fmt.Errorf("column %q: invalid precision %d", column.Name, *cfg.Precision)
+		}
+		column.Precision = *cfg.Precision
+	}
+
+	if column.fieldIndex < 0 {
+		return nil, fmt.Errorf("column %q: unknown field %q", column.Name, cfg.Field)
+	}
+
+	switch column.Type {
+	case RATIO_COLUMN:
+		column.byIndex = slices.Index(columns, cfg.By)
+		if column.byIndex < 0 {
+			return nil, fmt.Errorf("column %q: unknown divisor field %q", column.Name, cfg.By)
+		}
+	case DELTA_COLUMN:
+	default:
+		return nil, fmt.Errorf("column %q: unknown type %q", column.Name, cfg.Type)
+	}
+
+	return column, nil
+}
+
+// value computes the cell of the row, previous is the row of the same key in the previous tick or nil
+func (c *Column) value(row, previous []int) interface{} {
+	switch c.Type {
+	case RATIO_COLUMN:
+		if row[c.byIndex] == 0 {
+			return ""
+		}
+		return round(float64(row[c.fieldIndex])/float64(row[c.byIndex]), c.Precision)
+	case DELTA_COLUMN:
+		if previous == nil {
+			return ""
+		}
+		return row[c.fieldIndex] - previous[c.fieldIndex]
+	}
+
+	return ""
+}
+
+func round(value float64, precision int) float64 {
+	scale := math.Pow(10, float64(precision))
+	return math.Round(value*scale) / scale
+}
@@ -0,0 +1,168 @@
+package transform
+
+import (
+	"errors"
+	"fmt"
+	"wb-assistance-logistic/config"
+	"wb-assistance-logistic/snapshot"
+)
+
+const DEFAULT_TOTALS_LABEL = "Итого"
+
+// Result is the output of a tick prepared for the sheet
+type Result struct {
+	Columns []string        // The parsed columns followed by the computed ones
+	Rows    [][]interface{} // The rows with the computed columns and the totals row at the end when enabled
+	Summary [][]interface{} // Name and value of every aggregate, nil without aggregates
+
+	current map[int][]int
+	totals  []int
+}
+
+// Transformer computes the configured columns, totals and aggregates of every parsed snapshot.
+// It keeps the rows of the last written tick in memory for the delta columns, so the first tick after a start has no deltas.
+type Transformer struct {
+	columns       []*Column
+	aggregates    []*Aggregate
+	isTotals      bool
+	totalsLabel   string
+	summaryAnchor string
+
+	keyIndex       int
+	sourceColumns  []string
+	previous       map[int][]int
+	previousTotals []int
+}
+
+// NewTransformerByConfig validates the config against the columns of the parsed rows
+func NewTransformerByConfig(cfg *config.Transform, keyColumn string, columns []string) (*Transformer, error) {
+	keyIndex := -1
+	for i, column := range columns {
+		if column == keyColumn {
+			keyIndex = i
+		}
+	}
+
+	if keyIndex < 0 {
+		return nil, fmt.Errorf("key column %q is not in the columns", keyColumn)
+	}
+
+	t := &Transformer{
+		isTotals:      cfg.Totals,
+		totalsLabel:   cfg.TotalsLabel,
+		summaryAnchor: cfg.SummaryAnchor,
+		keyIndex:      keyIndex,
+		sourceColumns: append([]string(nil), columns...),
+	}
+
+	if t.totalsLabel == "" {
+		t.totalsLabel = DEFAULT_TOTALS_LABEL
+	}
+
+	names := map[string]bool{}
+	for _, column := range columns {
+		names[column] = true
+	}
+
+	for _, columnCfg := range cfg.Columns {
+		column, err := newColumnByConfig(columnCfg, columns)
+		if err != nil {
+			return nil, err
+		}
+
+		if names[column.Name] {
+			return nil, fmt.Errorf("duplicate column name %q", column.Name)
+		}
+		names[column.Name] = true
+
+		t.columns = append(t.columns, column)
+	}
+
+	for _, aggregateCfg := range cfg.Aggregates {
+		aggregate, err := newAggregateByConfig(aggregateCfg, columns)
+		if err != nil {
+			return nil, err
+		}
+
+		t.aggregates = append(t.aggregates, aggregate)
+	}
+
+	if len(t.aggregates) > 0 && t.summaryAnchor == "" {
+		return nil, errors.New("summary anchor is required for the aggregates")
+	}
+
+	return t, nil
+}
+
+// SummaryAnchor returns the top left cell of the summary block
+func (t *Transformer) SummaryAnchor() string {
+	return t.summaryAnchor
+}
+
+// Apply computes the result of the snapshot, the deltas are taken against the last committed result
+func (t *Transformer) Apply(s *snapshot.Snapshot) *Result {
+	result := &Result{
+		Columns: append([]string(nil), t.sourceColumns...),
+		current: make(map[int][]int, len(s.Rows)),
+	}
+	for _, column := range t.columns {
+		result.Columns = append(result.Columns, column.Name)
+	}
+
+	for _, row := range s.Rows {
+		key := row[t.keyIndex]
+		result.current[key] = row
+
+		result.Rows = append(result.Rows, t.row(row, t.previous[key]))
+	}
+
+	if t.isTotals {
+		result.totals = t.totals(s.Rows)
+
+		cells := t.row(result.totals, t.previousTotals)
+		cells[t.keyIndex] = t.totalsLabel
+		result.Rows = append(result.Rows, cells)
+	}
+
+	for _, aggregate := range t.aggregates {
+		result.Summary = append(result.Summary, []interface{}{aggregate.Name, aggregate.value(s.Rows)})
+	}
+
+	return result
+}
+
+// Commit remembers the rows of the result for the next delta, it is called once the result is written,
+// so a failed write keeps the deltas against the rows the sheet still shows
+func (t *Transformer) Commit(result *Result) {
+	t.previous = result.current
+	t.previousTotals = result.totals
+}
+
+// row returns the parsed values followed by the computed columns
+func (t *Transformer) row(row, previous []int) []interface{} {
+	cells := make([]interface{}, 0, len(row)+len(t.columns))
+	for _, value := range row {
+		cells = append(cells, value)
+	}
+
+	for _, column := range t.columns {
+		cells = append(cells, column.value(row, previous))
+	}
+
+	return cells
+}
+
+// totals sums every column except the key one
+func (t *Transformer) totals(rows [][]int) []int {
+	totals := make([]int, len(t.sourceColumns))
+
+	for _, row := range rows {
+		for i, value := range row {
+			if i != t.keyIndex && i < len(totals) {
+				totals[i] += value
+			}
+		}
+	}
+
+	return totals
+}
@@ -0,0 +1,151 @@
+package transform
+
+import (
+	"reflect"
+	"testing"
+	"wb-assistance-logistic/config"
+	"wb-assistance-logistic/snapshot"
+)
+
+var testColumns = []string{"Парковка", "Коробок", "Баркоды"}
+
+func newTestTransformer(t *testing.T, cfg *config.Transform) *Transformer {
+	t.Helper()
+
+	transformer, err := NewTransformerByConfig(cfg, "Парковка", testColumns)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return transformer
+}
+
+func deltaConfig() *config.Transform {
+	return &config.Transform{
+		Columns: []*config.TransformColumn{{Name: "Изменение", Type: string(DELTA_COLUMN), Field: "Коробок"}},
+	}
+}
+
+func TestColumnValue(t *testing.T) {
+	precision := 0
+
+	tests := []struct {
+		name     string
+		cfg      *config.TransformColumn
+		row      []int
+		previous []int
+		want     interface{}
+	}{
+		{"ratio", &config.TransformColumn{Name: "r", Type: "ratio", Field: "Коробок", By: "Баркоды"}, []int{1, 7, 3}, nil, 2.33},
+		{"ratio precision", &config.TransformColumn{Name: "r", Type: "ratio", Field: "Коробок", By: "Баркоды", Precision: &precision}, []int{1, 7, 2}, nil, 4.0},
+		{"ratio by zero", &config.TransformColumn{Name: "r", Type: "ratio", Field: "Коробок", By: "Баркоды"}, []int{1, 7, 0}, nil, ""},
+		{"delta", &config.TransformColumn{Name: "d", Type: "delta", Field: "Коробок"}, []int{1, 7, 3}, []int{1, 10, 3}, -3},
+		{"delta without the previous row", &config.TransformColumn{Name: "d", Type: "delta", Field: "Коробок"}, []int{1, 7, 3}, nil, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			column, err := newColumnByConfig(tt.cfg, testColumns)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got := column.value(tt.row, tt.previous); got != tt.want {
+				t.Errorf("value(%v, %v) = %v, want %v", tt.row, tt.previous, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestApplyDelta(t *testing.T) {
+	transformer := newTestTransformer(t, deltaConfig())
+
+	first := transformer.Apply(&snapshot.Snapshot{Rows: [][]int{{1, 10, 2}, {2, 5, 1}}})
+	want := [][]interface{}{{1, 10, 2, ""}, {2, 5, 1, ""}}
+	if !reflect.DeepEqual(first.Rows, want) {
+		t.Errorf("first Apply() rows = %v, want %v", first.Rows, want)
+	}
+	transformer.Commit(first)
+
+	// The key 3 is missing in the previous tick
+	second := transformer.Apply(&snapshot.Snapshot{Rows: [][]int{{1, 12, 2}, {3, 4, 1}}})
+	want = [][]interface{}{{1, 12, 2, 2}, {3, 4, 1, ""}}
+	if !reflect.DeepEqual(second.Rows, want) {
+		t.Errorf("second Apply() rows = %v, want %v", second.Rows, want)
+	}
+}
+
+func TestApplyWithoutCommit(t *testing.T) {
+	transformer := newTestTransformer(t, deltaConfig())
+
+	transformer.Commit(transformer.Apply(&snapshot.Snapshot{Rows: [][]int{{1, 10, 2}}}))
+
+	// The result of a failed write is not committed, the next deltas are still against the committed rows
+	transformer.Apply(&snapshot.Snapshot{Rows: [][]int{{1, 30, 2}}})
+
+	got := transformer.Apply(&snapshot.Snapshot{Rows: [][]int{{1, 15, 2}}})
+	want := [][]interface{}{{1, 15, 2, 5}}
+	if !reflect.DeepEqual(got.Rows, want) {
+		t.Errorf("Apply() rows = %v, want %v", got.Rows, want)
+	}
+}
+
+func TestApplyTotals(t *testing.T) {
+	tests := []struct {
+		name  string
+		label string
+		want  string
+	}{
+		{"default label", "", DEFAULT_TOTALS_LABEL},
+		{"custom label", "Всего", "Всего"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := deltaConfig()
+			cfg.Totals = true
+			cfg.TotalsLabel = tt.label
+			transformer := newTestTransformer(t, cfg)
+
+			transformer.Commit(transformer.Apply(&snapshot.Snapshot{Rows: [][]int{{1, 10, 2}, {2, 5, 1}}}))
+			result := transformer.Apply(&snapshot.Snapshot{Rows: [][]int{{1, 12, 2}, {2, 6, 4}}})
+
+			// The totals row is the last one, labelled in the key column and with the delta of the totals
+			totals := result.Rows[len(result.Rows)-1]
+			want := []interface{}{tt.want, 18, 6, 3}
+			if !reflect.DeepEqual(totals, want) {
+				t.Errorf("totals row = %v, want %v", totals, want)
+			}
+		})
+	}
+}
+
+func TestAggregateValue(t *testing.T) {
+	threshold := 5.0
+
+	tests := []struct {
+		name string
+		cfg  *config.TransformAggregate
+		rows [][]int
+		want interface{}
+	}{
+		{"sum", &config.TransformAggregate{Name: "a", Type: "sum", Field: "Коробок"}, [][]int{{1, 10, 2}, {2, 5, 1}}, 15},
+		{"avg", &config.TransformAggregate{Name: "a", Type: "avg", Field: "Коробок"}, [][]int{{1, 10, 2}, {2, 5, 1}}, 7.5},
+		{"avg without rows", &config.TransformAggregate{Name: "a", Type: "avg", Field: "Коробок"}, nil, ""},
+		{"sum without rows", &config.TransformAggregate{Name: "a", Type: "sum", Field: "Коробок"}, nil, 0},
+		{"count by condition", &config.TransformAggregate{Name: "a", Type: "count", Field: "Коробок", Op: ">", Value: &threshold}, [][]int{{1, 10, 2}, {2, 5, 1}}, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			aggregate, err := newAggregateByConfig(tt.cfg, testColumns)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got := aggregate.value(tt.rows); got != tt.want {
+				t.Errorf("value(%v) = %v, want %v", tt.rows, got, tt.want)
+			}
+		})
+	}
+}
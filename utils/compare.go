@@ -0,0 +1,26 @@
+package utils
+
+import (
+	"cmp"
+	"fmt"
+)
+
+// Compare applies the comparison operator (">", ">=", "<", "<=", "==" or "!=") to the values
+func Compare[T cmp.Ordered](a T, op string, b T) (bool, error) {
+	switch op {
+	case ">":
+		return a > b, nil
+	case ">=":
+		return a >= b, nil
+	case "<":
+		return a < b, nil
+	case "<=":
+		return a <= b, nil
+	case "==":
+		return a == b, nil
+	case "!=":
+		return a != b, nil
+	}
+
+	return false, fmt.Errorf("unknown comparison operator %q", op)
+}
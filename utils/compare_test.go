@@ -0,0 +1,39 @@
+package utils
+
+import "testing"
+
+func TestCompare(t *testing.T) {
+	tests := []struct {
+		a       float64
+		op      string
+		b       float64
+		want    bool
+		wantErr bool
+	}{
+		{2, ">", 1, true, false},
+		{1, ">", 1, false, false},
+		{1, ">=", 1, true, false},
+		{0.5, ">=", 1, false, false},
+		{1, "<", 2, true, false},
+		{2, "<", 2, false, false},
+		{2, "<=", 2, true, false},
+		{3, "<=", 2, false, false},
+		{1.5, "==", 1.5, true, false},
+		{1, "==", 2, false, false},
+		{1, "!=", 2, true, false},
+		{2, "!=", 2, false, false},
+		{1, "=", 1, false, true},
+		{1, "", 1, false, true},
+	}
+
+	for _, tt := range tests {
+		got, err := Compare(tt.a, tt.op, tt.b)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("Compare(%g, %q, %g) error = %v, want error %v", tt.a, tt.op, tt.b, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("Compare(%g, %q, %g) = %v, want %v", tt.a, tt.op, tt.b, got, tt.want)
+		}
+	}
+}